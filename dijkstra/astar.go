@@ -0,0 +1,43 @@
+package dijkstra
+
+import (
+	"errors"
+	"math"
+
+	"github.com/dimuls/graph/entity"
+)
+
+var ErrInvalidScale = errors.New("invalid heuristic scale")
+
+// AStarShortestPath builds graph from the given vertexes and edges and
+// returns the shortest path between from and to vertexes found by A*
+// search.
+func AStarShortestPath(vs []entity.Vertex, es []entity.Edge,
+	from int64, to int64, scale float64) (Path, error) {
+
+	return NewGraph(vs, es).AStarShortestPath(from, to, scale)
+}
+
+// AStarShortestPath returns the shortest path between from and to
+// vertexes found by A* search. Euclidean distance between vertex
+// coordinates multiplied by scale is used as heuristic, so scale must not
+// exceed the smallest ratio of edge weight to edge length for the found
+// path to be the shortest one. Zero scale makes it plain Dijkstra.
+func (g *Graph) AStarShortestPath(from int64, to int64,
+	scale float64) (Path, error) {
+
+	if scale < 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return Path{}, ErrInvalidScale
+	}
+
+	dst, exists := g.index[to]
+	if !exists {
+		return Path{}, entity.ErrVertexNotFound
+	}
+
+	x, y := g.vertexes[dst].X, g.vertexes[dst].Y
+
+	return g.search(from, to, func(v int) float64 {
+		return scale * math.Hypot(g.vertexes[v].X-x, g.vertexes[v].Y-y)
+	})
+}
@@ -0,0 +1,87 @@
+package dijkstra
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestAStarShortestPath(t *testing.T) {
+	vs := []entity.Vertex{
+		{ID: 0, X: 0, Y: 0},
+		{ID: 1, X: 1, Y: 0},
+		{ID: 2, X: 2, Y: 0},
+		{ID: 3, X: 0, Y: 5},
+	}
+	es := []entity.Edge{
+		{ID: 0, From: 0, To: 1, Weight: 1},
+		{ID: 1, From: 1, To: 2, Weight: 1},
+		{ID: 2, From: 0, To: 3, Weight: 5},
+		{ID: 3, From: 3, To: 2, Weight: 6},
+	}
+
+	got, err := NewGraph(vs, es).AStarShortestPath(0, 2, 1)
+	if err != nil {
+		t.Fatalf("AStarShortestPath() error = %v", err)
+	}
+	want := Path{
+		EdgeIDs:   []int64{0, 1},
+		VertexIDs: []int64{0, 1, 2},
+		TotalCost: 2,
+		Hops:      2,
+		Explored:  3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AStarShortestPath() got = %+v, want %+v", got, want)
+	}
+
+	_, err = NewGraph(vs, es).AStarShortestPath(0, 2, -1)
+	if err != ErrInvalidScale {
+		t.Errorf("AStarShortestPath() error = %v, want %v",
+			err, ErrInvalidScale)
+	}
+}
+
+func TestAStarShortestPath_random(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 5000; i++ {
+		vs, es := randomGraph(r, 7, 15)
+
+		// Make weights geometric so unit scale heuristic is admissible.
+		for j := range es {
+			a, b := vs[es[j].From], vs[es[j].To]
+			es[j].Weight = math.Hypot(a.X-b.X, a.Y-b.Y) * (1 + r.Float64())
+		}
+
+		from := vs[r.Intn(len(vs))].ID
+		to := vs[r.Intn(len(vs))].ID
+
+		g := NewGraph(vs, es)
+
+		want, wantErr := g.ShortestPath(from, to)
+		got, err := g.AStarShortestPath(from, to, 1)
+
+		if err != wantErr {
+			t.Fatalf("graph %d: AStarShortestPath() error = %v, want %v",
+				i, err, wantErr)
+		}
+		if err != nil {
+			continue
+		}
+
+		cost := pathCost(t, es, got.EdgeIDs, from, to)
+		if math.Abs(cost-want.TotalCost) > 1e-9 {
+			t.Fatalf("graph %d: AStarShortestPath() cost = %v, want %v",
+				i, cost, want.TotalCost)
+		}
+		if got.Explored > want.Explored {
+			t.Fatalf("graph %d: AStarShortestPath() explored %d "+
+				"vertexes, dijkstra explored %d", i, got.Explored,
+				want.Explored)
+		}
+	}
+}
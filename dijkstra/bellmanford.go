@@ -0,0 +1,110 @@
+package dijkstra
+
+import (
+	"fmt"
+)
+
+// NegativeCycleError is returned when the shortest path is undefined
+// because of negative cycle between the path ends.
+type NegativeCycleError struct {
+	EdgeIDs []int64
+}
+
+func (e *NegativeCycleError) Error() string {
+	return fmt.Sprintf("negative cycle of edges %v", e.EdgeIDs)
+}
+
+// bellmanFord builds the shortest path tree from src vertex in the graph
+// with negative edge weights skipping edges hidden by m.
+func (g *Graph) bellmanFord(src int, m *mask) *tree {
+	t := newTree(len(g.ids), src)
+
+	relax := func() (relaxed []int) {
+		for v := range g.outEdges {
+			if !t.reachable(v) {
+				continue
+			}
+			for i := range g.outEdges[v] {
+				e := &g.outEdges[v][i]
+				if m.hides(e) {
+					continue
+				}
+				d := t.distances[v] + e.weight
+				if d < t.distances[e.to] {
+					t.distances[e.to] = d
+					t.prev[e.to] = e
+					relaxed = append(relaxed, e.to)
+				}
+			}
+		}
+		return
+	}
+
+	for i := 1; i < len(g.ids); i++ {
+		if len(relax()) == 0 {
+			break
+		}
+	}
+
+	for v := range t.distances {
+		if t.reachable(v) {
+			t.explored++
+		}
+	}
+
+	relaxed := relax()
+	if len(relaxed) == 0 {
+		return t
+	}
+
+	// Vertexes relaxed after |V|-1 iterations are reachable from negative
+	// cycles, so are all vertexes reachable from them.
+	t.cycles = make([]int, len(g.ids))
+	for i := range t.cycles {
+		t.cycles[i] = -1
+	}
+	for _, v := range relaxed {
+		t.cycles[v] = v
+	}
+	stack := append([]int(nil), relaxed...)
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for i := range g.outEdges[v] {
+			e := &g.outEdges[v][i]
+			if m.hides(e) {
+				continue
+			}
+			if t.cycles[e.to] < 0 {
+				t.cycles[e.to] = t.cycles[v]
+				stack = append(stack, e.to)
+			}
+		}
+	}
+
+	return t
+}
+
+// cycleEdgeIDs returns IDs of edges forming the cycle in prev which leads
+// to v vertex.
+func (g *Graph) cycleEdgeIDs(prev []*edge, v int) []int64 {
+	for i := 0; i < len(g.ids); i++ {
+		v = prev[v].from
+	}
+
+	var cycle []int64
+	for u := v; ; {
+		e := prev[u]
+		cycle = append(cycle, e.id)
+		u = e.from
+		if u == v {
+			break
+		}
+	}
+
+	for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+		cycle[i], cycle[j] = cycle[j], cycle[i]
+	}
+
+	return cycle
+}
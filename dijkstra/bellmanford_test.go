@@ -0,0 +1,137 @@
+package dijkstra
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestShortestPath_negativeCycle(t *testing.T) {
+	vs := []entity.Vertex{{}, {ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	es := []entity.Edge{
+		{ID: 0, From: 0, To: 1, Weight: 1},
+		{ID: 1, From: 1, To: 2, Weight: 1},
+		{ID: 2, From: 2, To: 1, Weight: -3},
+		{ID: 3, From: 2, To: 3, Weight: 1},
+		{ID: 4, From: 0, To: 4, Weight: -1},
+	}
+
+	_, err := ShortestPath(vs, es, 0, 3)
+
+	var cycleErr *NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("ShortestPath() error = %v, want NegativeCycleError", err)
+	}
+
+	ids := map[int64]bool{}
+	for _, id := range cycleErr.EdgeIDs {
+		ids[id] = true
+	}
+	if len(ids) != 2 || !ids[1] || !ids[2] {
+		t.Errorf("ShortestPath() cycle = %v, want edges 1 and 2",
+			cycleErr.EdgeIDs)
+	}
+
+	got, err := ShortestPath(vs, es, 0, 4)
+	if err != nil {
+		t.Fatalf("ShortestPath() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.EdgeIDs, []int64{4}) {
+		t.Errorf("ShortestPath() got = %v, want [4]", got.EdgeIDs)
+	}
+}
+
+// hasNegativeCycle reports whether there is a negative cycle on some walk
+// between from and to vertexes.
+func hasNegativeCycle(vs []entity.Vertex, es []entity.Edge,
+	from int64, to int64) bool {
+
+	n := len(vs)
+
+	d := make([][]float64, n)
+	for i := range d {
+		d[i] = make([]float64, n)
+		for j := range d[i] {
+			if i != j {
+				d[i][j] = math.Inf(1)
+			}
+		}
+	}
+	for _, e := range arcs(es) {
+		d[e.From][e.To] = math.Min(d[e.From][e.To], e.Weight)
+	}
+
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if d[i][k]+d[k][j] < d[i][j] {
+					d[i][j] = d[i][k] + d[k][j]
+				}
+			}
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		if d[v][v] < 0 && !math.IsInf(d[from][v], 1) &&
+			!math.IsInf(d[v][to], 1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestShortestPath_randomNegative(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+
+	for i := 0; i < 5000; i++ {
+		vs, es := randomGraph(r, 7, 12)
+		for j := range es {
+			es[j].Weight -= 3
+		}
+
+		from := vs[r.Intn(len(vs))].ID
+		to := vs[r.Intn(len(vs))].ID
+		if from == to {
+			continue
+		}
+
+		got, err := ShortestPath(vs, es, from, to)
+
+		if hasNegativeCycle(vs, es, from, to) {
+			var cycleErr *NegativeCycleError
+			if !errors.As(err, &cycleErr) {
+				t.Fatalf("graph %d: ShortestPath() error = %v, "+
+					"want NegativeCycleError", i, err)
+			}
+			cost := cycleCost(t, es, cycleErr.EdgeIDs)
+			if cost >= 0 {
+				t.Fatalf("graph %d: ShortestPath() cycle cost = %v",
+					i, cost)
+			}
+			continue
+		}
+
+		want, connected := bruteForceDistance(vs, es, from, to)
+		if !connected {
+			if err != ErrNotConnected {
+				t.Fatalf("graph %d: ShortestPath() error = %v, want %v",
+					i, err, ErrNotConnected)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("graph %d: ShortestPath() error = %v", i, err)
+		}
+
+		cost := pathCost(t, es, got.EdgeIDs, from, to)
+		if math.Abs(cost-want) > 1e-9 {
+			t.Fatalf("graph %d: ShortestPath() cost = %v, want %v",
+				i, cost, want)
+		}
+	}
+}
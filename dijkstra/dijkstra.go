@@ -1,108 +1,225 @@
 package dijkstra
 
 import (
+	"container/heap"
 	"errors"
+	"math"
 
 	"github.com/dimuls/graph/entity"
 )
 
-type edge struct {
-	id     int64
-	weight float64
-	from   int64
-	to     int64
+var ErrNotConnected = errors.New("vertexes are not connected")
+
+type item struct {
+	vertex   int
+	distance float64
+	priority float64
+}
+
+type queue []item
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].vertex < q[j].vertex
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(item)) }
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// ShortestPath builds graph from the given vertexes and edges and returns
+// the shortest path between from and to vertexes.
+func ShortestPath(vs []entity.Vertex, es []entity.Edge,
+	from int64, to int64) (Path, error) {
+
+	return NewGraph(vs, es).ShortestPath(from, to)
 }
 
-type vertex struct {
-	id       int64
-	outEdges []edge
+// ShortestPath returns the shortest path between from and to vertexes.
+// Bellman-Ford algorithm is used instead of Dijkstra
+// if the graph has edges with negative weight, and NegativeCycleError is
+// returned if the path passes through a negative cycle.
+func (g *Graph) ShortestPath(from int64, to int64) (Path, error) {
+	return g.search(from, to, nil)
 }
 
-func initGraph(vs []entity.Vertex, es []entity.Edge) map[int64]vertex {
-	vsMap := map[int64]entity.Vertex{}
-	for _, v := range vs {
-		vsMap[v.ID] = v
+// search finds the shortest path between from and to vertexes using
+// heuristic as lower bound estimate of the distance to the to vertex. Nil
+// heuristic turns the search into plain Dijkstra.
+func (g *Graph) search(from int64, to int64,
+	heuristic func(v int) float64) (Path, error) {
+
+	src, exists := g.index[from]
+	if !exists {
+		return Path{}, entity.ErrVertexNotFound
 	}
 
-	esMap := map[int64]entity.Edge{}
-	for _, e := range es {
-		esMap[e.ID] = e
+	dst, exists := g.index[to]
+	if !exists {
+		return Path{}, entity.ErrVertexNotFound
 	}
 
-	graph := map[int64]vertex{}
+	if src == dst {
+		return g.newPath(src, nil), nil
+	}
 
-	for _, v := range vs {
-		v2 := vertex{
-			id:       v.ID,
-			outEdges: nil,
-		}
-		for _, e := range es {
-			if e.From == v.ID {
-				v2.outEdges = append(v2.outEdges, edge{
-					id:     e.ID,
-					weight: e.Weight,
-					from:   e.From,
-					to:     e.To,
-				})
-			}
-		}
-		graph[v.ID] = v2
+	edges, explored, err := g.route(src, dst, heuristic, nil)
+	if err != nil {
+		return Path{}, err
 	}
 
-	return graph
+	p := g.newPath(src, edges)
+	p.Explored = explored
+
+	return p, nil
 }
 
-func ShortestPath(vs []entity.Vertex, es []entity.Edge,
-	from int64, to int64) ([]int64, error) {
+// mask hides vertexes and edges from route.
+type mask struct {
+	vertexes []bool
+	edges    map[*edge]bool
+}
 
-	if from == to {
-		return nil, nil
-	}
+func (m *mask) hides(e *edge) bool {
+	return m != nil && (m.vertexes[e.to] || m.edges[e])
+}
 
-	graph := initGraph(vs, es)
+// tree is the shortest path tree built by the search.
+type tree struct {
+	distances []float64
+	prev      []*edge
+	explored  int
 
-	visited := map[int64]struct{}{}
-	visited[from] = struct{}{}
+	// cycles maps vertexes reachable from negative cycles to vertexes
+	// from which the cycle can be found in prev. It is nil if there are
+	// no such vertexes.
+	cycles []int
+}
+
+func newTree(size int, src int) *tree {
+	t := &tree{
+		distances: make([]float64, size),
+		prev:      make([]*edge, size),
+	}
+	for i := range t.distances {
+		t.distances[i] = math.Inf(1)
+	}
+	t.distances[src] = 0
+	return t
+}
 
-	distances := map[int64]float64{}
-	paths := map[int64][]edge{}
+func (t *tree) reachable(v int) bool {
+	return !math.IsInf(t.distances[v], 1)
+}
 
-	edges := graph[from].outEdges
+// route finds the shortest path between src and dst vertexes skipping
+// edges hidden by m. Heuristic is ignored for graphs with negative edge
+// weights since Bellman-Ford algorithm is used for them. It returns path
+// edges and number of explored vertexes.
+func (g *Graph) route(src int, dst int, heuristic func(v int) float64,
+	m *mask) ([]*edge, int, error) {
+
+	var t *tree
+	if g.negative {
+		t = g.bellmanFord(src, m)
+	} else {
+		t = g.dijkstra(src, dst, heuristic, m)
+	}
 
-	for {
-		if len(edges) == 0 {
-			break
+	if t.cycles != nil && t.cycles[dst] >= 0 {
+		return nil, t.explored, &NegativeCycleError{
+			EdgeIDs: g.cycleEdgeIDs(t.prev, t.cycles[dst]),
 		}
+	}
+
+	if !t.reachable(dst) {
+		return nil, t.explored, ErrNotConnected
+	}
 
-		e := edges[0]
-		edges = edges[1:]
+	return pathEdges(t.prev, dst), t.explored, nil
+}
 
-		v := graph[e.to]
+// dijkstra builds the shortest path tree from src vertex skipping edges
+// hidden by m. The search stops when dst vertex is reached, negative dst
+// makes it build the full tree.
+func (g *Graph) dijkstra(src int, dst int, heuristic func(v int) float64,
+	m *mask) *tree {
 
-		d, exists := distances[v.id]
-		if !exists || d > distances[e.from]+e.weight {
-			distances[v.id] = distances[e.from] + e.weight
-			paths[v.id] = append(paths[e.from], e)
-		}
+	if heuristic == nil {
+		heuristic = func(int) float64 { return 0 }
+	}
+
+	t := newTree(len(g.ids), src)
+
+	q := &queue{{vertex: src, priority: heuristic(src)}}
+
+	for q.Len() > 0 {
+		it := heap.Pop(q).(item)
 
-		if _, exists := visited[v.id]; exists {
+		if it.distance > t.distances[it.vertex] {
 			continue
 		}
 
-		edges = append(edges, v.outEdges...)
+		t.explored++
 
-		visited[v.id] = struct{}{}
+		if it.vertex == dst {
+			break
+		}
+
+		for i := range g.outEdges[it.vertex] {
+			e := &g.outEdges[it.vertex][i]
+			if m.hides(e) {
+				continue
+			}
+			d := it.distance + e.weight
+			if d < t.distances[e.to] {
+				t.distances[e.to] = d
+				t.prev[e.to] = e
+				heap.Push(q, item{
+					vertex:   e.to,
+					distance: d,
+					priority: d + heuristic(e.to),
+				})
+			}
+		}
 	}
 
-	var path []int64
+	return t
+}
 
-	for _, e := range paths[to] {
-		path = append(path, e.id)
+func pathEdges(prev []*edge, dst int) []*edge {
+	var n int
+	for e := prev[dst]; e != nil; e = prev[e.from] {
+		n++
 	}
 
-	if len(path) == 0 {
-		return nil, errors.New("vertexes are not connected")
+	path := make([]*edge, n)
+	for e := prev[dst]; e != nil; e = prev[e.from] {
+		n--
+		path[n] = e
 	}
 
-	return path, nil
+	return path
+}
+
+func edgeIDs(path []*edge) []int64 {
+	if len(path) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(path))
+	for i, e := range path {
+		ids[i] = e.id
+	}
+	return ids
 }
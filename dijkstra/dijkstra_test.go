@@ -1,6 +1,9 @@
 package dijkstra
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
 	"reflect"
 	"testing"
 
@@ -147,6 +150,92 @@ func TestShortestPath(t *testing.T) {
 			want:    []int64{2},
 			wantErr: false,
 		},
+		{
+			name: "cheaper path discovered after visit",
+			args: args{
+				vs: []entity.Vertex{{}, {ID: 1}, {ID: 2}, {ID: 3}},
+				es: []entity.Edge{{
+					ID:     0,
+					From:   0,
+					To:     1,
+					Weight: 1,
+				}, {
+					ID:     1,
+					From:   0,
+					To:     2,
+					Weight: 5,
+				}, {
+					ID:     2,
+					From:   1,
+					To:     2,
+					Weight: 1,
+				}, {
+					ID:     3,
+					From:   2,
+					To:     3,
+					Weight: 1,
+				}},
+				from: 0,
+				to:   3,
+			},
+			want:    []int64{0, 2, 3},
+			wantErr: false,
+		},
+		{
+			name: "undirected edge",
+			args: args{
+				vs: []entity.Vertex{{}, {ID: 1}, {ID: 2}},
+				es: []entity.Edge{{
+					ID:         0,
+					From:       0,
+					To:         1,
+					Weight:     1,
+					Undirected: true,
+				}, {
+					ID:     1,
+					From:   2,
+					To:     0,
+					Weight: 1,
+				}},
+				from: 1,
+				to:   0,
+			},
+			want:    []int64{0},
+			wantErr: false,
+		},
+		{
+			name: "undirected edge does not reverse directed one",
+			args: args{
+				vs: []entity.Vertex{{}, {ID: 1}, {ID: 2}},
+				es: []entity.Edge{{
+					ID:         0,
+					From:       0,
+					To:         1,
+					Weight:     1,
+					Undirected: true,
+				}, {
+					ID:     1,
+					From:   2,
+					To:     0,
+					Weight: 1,
+				}},
+				from: 0,
+				to:   2,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unknown vertex",
+			args: args{
+				vs:   []entity.Vertex{{}},
+				es:   nil,
+				from: 0,
+				to:   1,
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -155,13 +244,235 @@ func TestShortestPath(t *testing.T) {
 				t.Errorf("ShortestPath() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ShortestPath() got = %v, want %v", got, tt.want)
+			if !reflect.DeepEqual(got.EdgeIDs, tt.want) {
+				t.Errorf("ShortestPath() got = %v, want %v", got.EdgeIDs, tt.want)
 			}
 		})
 	}
 }
 
+func randomGraph(r *rand.Rand, maxVertexes, maxEdges int) (
+	[]entity.Vertex, []entity.Edge) {
+
+	vs := make([]entity.Vertex, 1+r.Intn(maxVertexes))
+	for i := range vs {
+		vs[i] = entity.Vertex{
+			ID: int64(i),
+			X:  r.Float64() * 100,
+			Y:  r.Float64() * 100,
+		}
+	}
+
+	es := make([]entity.Edge, r.Intn(maxEdges+1))
+	for i := range es {
+		es[i] = entity.Edge{
+			ID:         int64(i),
+			From:       int64(r.Intn(len(vs))),
+			To:         int64(r.Intn(len(vs))),
+			Weight:     float64(r.Intn(10)) + r.Float64(),
+			Undirected: r.Intn(3) == 0,
+		}
+	}
+
+	return vs, es
+}
+
+// arcs returns directed edges of the graph: undirected edges are replaced
+// with the pair of opposite edges with the same ID.
+func arcs(es []entity.Edge) []entity.Edge {
+	as := make([]entity.Edge, 0, len(es))
+	for _, e := range es {
+		if !e.Undirected {
+			as = append(as, e)
+			continue
+		}
+		e.Undirected = false
+		as = append(as, e)
+		if e.From != e.To {
+			e.From, e.To = e.To, e.From
+			as = append(as, e)
+		}
+	}
+	return as
+}
+
+// bruteForceDistance enumerates all simple paths between from and to and
+// returns the smallest cost among them.
+func bruteForceDistance(vs []entity.Vertex, es []entity.Edge,
+	from int64, to int64) (float64, bool) {
+
+	es = arcs(es)
+
+	best := math.Inf(1)
+	visited := map[int64]bool{from: true}
+
+	var walk func(v int64, cost float64)
+	walk = func(v int64, cost float64) {
+		if v == to {
+			if cost < best {
+				best = cost
+			}
+			return
+		}
+		for _, e := range es {
+			if e.From != v || visited[e.To] {
+				continue
+			}
+			visited[e.To] = true
+			walk(e.To, cost+e.Weight)
+			visited[e.To] = false
+		}
+	}
+
+	walk(from, 0)
+
+	return best, !math.IsInf(best, 1)
+}
+
+// followPath walks path from the vertex and returns its end and cost.
+// Undirected edges could be passed in any direction.
+func followPath(es []entity.Edge, path []int64, from int64) (
+	int64, float64, error) {
+
+	esMap := map[int64]entity.Edge{}
+	for _, e := range es {
+		esMap[e.ID] = e
+	}
+
+	var cost float64
+	v := from
+	for _, id := range path {
+		e, exists := esMap[id]
+		if !exists {
+			return 0, 0, fmt.Errorf("path contains unknown edge %d", id)
+		}
+		switch {
+		case e.From == v:
+			v = e.To
+		case e.Undirected && e.To == v:
+			v = e.From
+		default:
+			return 0, 0, fmt.Errorf("path is broken at edge %d", id)
+		}
+		cost += e.Weight
+	}
+
+	return v, cost, nil
+}
+
+// pathCost checks that path is a valid path between from and to and
+// returns its cost.
+func pathCost(t *testing.T, es []entity.Edge, path []int64,
+	from int64, to int64) float64 {
+
+	t.Helper()
+
+	v, cost, err := followPath(es, path, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != to {
+		t.Fatalf("path ends at %d, want %d", v, to)
+	}
+
+	return cost
+}
+
+// cycleCost checks that cycle is a valid cycle and returns its cost. The
+// cycle starts at either end of its first edge.
+func cycleCost(t *testing.T, es []entity.Edge, cycle []int64) float64 {
+	t.Helper()
+
+	first := es[cycle[0]]
+	for _, from := range []int64{first.From, first.To} {
+		v, cost, err := followPath(es, cycle, from)
+		if err == nil && v == from {
+			return cost
+		}
+	}
+
+	t.Fatalf("edges %v are not a cycle", cycle)
+	return 0
+}
+
+func TestShortestPath_random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		vs, es := randomGraph(r, 7, 15)
+		from := vs[r.Intn(len(vs))].ID
+		to := vs[r.Intn(len(vs))].ID
+
+		got, err := ShortestPath(vs, es, from, to)
+
+		if from == to {
+			if err != nil || got.EdgeIDs != nil || got.TotalCost != 0 {
+				t.Fatalf("graph %d: ShortestPath() = %+v, %v, want empty path",
+					i, got, err)
+			}
+			continue
+		}
+
+		want, connected := bruteForceDistance(vs, es, from, to)
+		if !connected {
+			if err != ErrNotConnected {
+				t.Fatalf("graph %d: ShortestPath() error = %v, want %v",
+					i, err, ErrNotConnected)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("graph %d: ShortestPath() error = %v", i, err)
+		}
+
+		cost := pathCost(t, es, got.EdgeIDs, from, to)
+		if math.Abs(cost-want) > 1e-9 {
+			t.Fatalf("graph %d: ShortestPath() cost = %v, want %v",
+				i, cost, want)
+		}
+		if math.Abs(got.TotalCost-cost) > 1e-9 {
+			t.Fatalf("graph %d: ShortestPath() total cost = %v, want %v",
+				i, got.TotalCost, cost)
+		}
+		if got.Hops != len(got.EdgeIDs) ||
+			len(got.VertexIDs) != got.Hops+1 ||
+			got.VertexIDs[0] != from || got.VertexIDs[got.Hops] != to {
+			t.Fatalf("graph %d: ShortestPath() got inconsistent path %+v",
+				i, got)
+		}
+	}
+}
+
+func BenchmarkGraph_ShortestPath(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+
+	const vertexes = 10000
+	const edges = 100000
+
+	vs := make([]entity.Vertex, vertexes)
+	for i := range vs {
+		vs[i] = entity.Vertex{ID: int64(i)}
+	}
+
+	es := make([]entity.Edge, edges)
+	for i := range es {
+		es[i] = entity.Edge{
+			ID:     int64(i),
+			From:   int64(r.Intn(vertexes)),
+			To:     int64(r.Intn(vertexes)),
+			Weight: r.Float64() * 100,
+		}
+	}
+
+	g := NewGraph(vs, es)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.ShortestPath(int64(r.Intn(vertexes)), int64(r.Intn(vertexes)))
+	}
+}
+
 func BenchmarkShortestPath(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		ShortestPath([]entity.Vertex{{}, {ID: 1}, {ID: 2}},
@@ -0,0 +1,278 @@
+package dijkstra
+
+import (
+	"compress/gzip"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/graphio"
+)
+
+// DIMACS benchmarks run against road graphs of 9th DIMACS challenge from
+// the directory set by DIMACS_DIR environment variable, for example:
+//
+//	DIMACS_DIR=~/dimacs go test -run '^$' -bench DIMACS ./dijkstra
+//
+// Every NAME.gr file is loaded with NAME.co coordinates. Queries are read
+// from NAME.p2p and NAME.ss files, random queries are used when they are
+// absent. All files may be gzipped.
+const dimacsDirEnv = "DIMACS_DIR"
+
+const (
+	// dimacsRandomQueries is the number of random queries.
+	dimacsRandomQueries = 1000
+
+	// dimacsMatrixVertexes limits the size of graphs for all pairs
+	// algorithms.
+	dimacsMatrixVertexes = 5000
+
+	// dimacsK is the number of paths found by Yen algorithm.
+	dimacsK = 3
+)
+
+type dimacsGraph struct {
+	name  string
+	g     *Graph
+	p2p   []graphio.DIMACSQuery
+	ss    []graphio.DIMACSQuery
+	scale float64
+}
+
+// openDIMACS opens the file with the given path or its gzipped version.
+// It returns nil reader if neither exists.
+func openDIMACS(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err == nil {
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err = os.Open(path + ".gz")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
+
+func loadDIMACSQueries(b *testing.B, path string) []graphio.DIMACSQuery {
+	r, err := openDIMACS(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if r == nil {
+		return nil
+	}
+	defer r.Close()
+
+	qs, err := graphio.ReadDIMACSQueries(r)
+	if err != nil {
+		b.Fatalf("%s: %v", path, err)
+	}
+
+	return qs
+}
+
+func loadDIMACSGraph(b *testing.B, base string) dimacsGraph {
+	gr, err := openDIMACS(base + ".gr")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer gr.Close()
+
+	co, err := openDIMACS(base + ".co")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var vs []entity.Vertex
+	var es []entity.Edge
+
+	if co == nil {
+		vs, es, err = graphio.ReadDIMACS(gr, nil)
+	} else {
+		defer co.Close()
+		vs, es, err = graphio.ReadDIMACS(gr, co)
+	}
+	if err != nil {
+		b.Fatalf("%s: %v", base, err)
+	}
+
+	d := dimacsGraph{
+		name: filepath.Base(base),
+		g:    NewGraph(vs, es),
+		p2p:  loadDIMACSQueries(b, base+".p2p"),
+		ss:   loadDIMACSQueries(b, base+".ss"),
+	}
+
+	r := rand.New(rand.NewSource(1))
+
+	if d.p2p == nil {
+		for i := 0; i < dimacsRandomQueries; i++ {
+			d.p2p = append(d.p2p, graphio.DIMACSQuery{
+				From: int64(r.Intn(len(vs)) + 1),
+				To:   int64(r.Intn(len(vs)) + 1),
+			})
+		}
+	}
+
+	if d.ss == nil {
+		for i := 0; i < dimacsRandomQueries; i++ {
+			d.ss = append(d.ss, graphio.DIMACSQuery{
+				From: int64(r.Intn(len(vs)) + 1),
+			})
+		}
+	}
+
+	// The heuristic scale is admissible only with coordinates, otherwise
+	// A* falls back to plain Dijkstra.
+	if co != nil {
+		d.scale = math.Inf(1)
+		for _, e := range es {
+			from, to := vs[e.From-1], vs[e.To-1]
+			l := math.Hypot(from.X-to.X, from.Y-to.Y)
+			if l > 0 && e.Weight/l < d.scale {
+				d.scale = e.Weight / l
+			}
+		}
+		if math.IsInf(d.scale, 1) {
+			d.scale = 0
+		}
+	}
+
+	return d
+}
+
+func loadDIMACSGraphs(b *testing.B) []dimacsGraph {
+	dir := os.Getenv(dimacsDirEnv)
+	if dir == "" {
+		b.Skip(dimacsDirEnv + " is not set")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.gr*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var bases []string
+	seen := map[string]bool{}
+
+	for _, p := range paths {
+		base := strings.TrimSuffix(strings.TrimSuffix(p, ".gz"), ".gr")
+		if base == p || seen[base] {
+			continue
+		}
+		seen[base] = true
+		bases = append(bases, base)
+	}
+
+	if len(bases) == 0 {
+		b.Skip("no DIMACS graphs in " + dir)
+	}
+
+	gs := make([]dimacsGraph, 0, len(bases))
+	for _, base := range bases {
+		gs = append(gs, loadDIMACSGraph(b, base))
+	}
+
+	return gs
+}
+
+func BenchmarkDIMACS(b *testing.B) {
+	gs := loadDIMACSGraphs(b)
+
+	for _, d := range gs {
+		d := d
+
+		b.Run(d.name+"/ShortestPath", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q := d.p2p[i%len(d.p2p)]
+				_, err := d.g.ShortestPath(q.From, q.To)
+				if err != nil && err != ErrNotConnected {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(d.name+"/AStarShortestPath", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q := d.p2p[i%len(d.p2p)]
+				_, err := d.g.AStarShortestPath(q.From, q.To, d.scale)
+				if err != nil && err != ErrNotConnected {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(d.name+"/KShortestPaths", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q := d.p2p[i%len(d.p2p)]
+				_, err := d.g.KShortestPaths(q.From, q.To, dimacsK)
+				if err != nil && err != ErrNotConnected {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(d.name+"/ShortestPathTree", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := d.g.ShortestPathTree(d.ss[i%len(d.ss)].From)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(d.name+"/BellmanFord", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				src, exists := d.g.index[d.ss[i%len(d.ss)].From]
+				if !exists {
+					b.Fatal(entity.ErrVertexNotFound)
+				}
+				d.g.bellmanFord(src, nil)
+			}
+		})
+
+		if len(d.g.ids) > dimacsMatrixVertexes {
+			continue
+		}
+
+		b.Run(d.name+"/Johnson", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := d.g.Johnson()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(d.name+"/FloydWarshall", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := d.g.FloydWarshall()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
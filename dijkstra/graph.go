@@ -0,0 +1,83 @@
+package dijkstra
+
+import (
+	"github.com/dimuls/graph/entity"
+)
+
+type edge struct {
+	id     int64
+	weight float64
+	from   int
+	to     int
+}
+
+// Graph is an adjacency list representation of the graph which is built
+// once and can be reused for many path searches.
+type Graph struct {
+	ids      []int64
+	index    map[int64]int
+	vertexes []entity.Vertex
+	outEdges [][]edge
+	edges    int
+	negative bool
+}
+
+// NewGraph builds Graph from the given vertexes and edges. Undirected edges
+// can be traversed in both directions. Edges which reference unknown
+// vertexes are ignored.
+func NewGraph(vs []entity.Vertex, es []entity.Edge) *Graph {
+	g := &Graph{
+		ids:      make([]int64, 0, len(vs)),
+		index:    make(map[int64]int, len(vs)),
+		vertexes: make([]entity.Vertex, 0, len(vs)),
+	}
+
+	for _, v := range vs {
+		if _, exists := g.index[v.ID]; exists {
+			continue
+		}
+		g.index[v.ID] = len(g.ids)
+		g.ids = append(g.ids, v.ID)
+		g.vertexes = append(g.vertexes, v)
+	}
+
+	g.outEdges = make([][]edge, len(g.ids))
+
+	for _, e := range es {
+		from, exists := g.index[e.From]
+		if !exists {
+			continue
+		}
+		to, exists := g.index[e.To]
+		if !exists {
+			continue
+		}
+		if e.Weight < 0 {
+			g.negative = true
+		}
+		g.outEdges[from] = append(g.outEdges[from], edge{
+			id:     e.ID,
+			weight: e.Weight,
+			from:   from,
+			to:     to,
+		})
+		g.edges++
+		if e.Undirected && from != to {
+			g.outEdges[to] = append(g.outEdges[to], edge{
+				id:     e.ID,
+				weight: e.Weight,
+				from:   to,
+				to:     from,
+			})
+			g.edges++
+		}
+	}
+
+	return g
+}
+
+// HasVertex reports whether the graph contains vertex with the given ID.
+func (g *Graph) HasVertex(id int64) bool {
+	_, exists := g.index[id]
+	return exists
+}
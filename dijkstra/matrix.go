@@ -0,0 +1,207 @@
+package dijkstra
+
+import (
+	"math"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// Matrix is the matrix of distances between all pairs of vertexes.
+type Matrix struct {
+	VertexIDs []int64
+
+	// Distances[i][j] is the distance from VertexIDs[i] to VertexIDs[j]
+	// vertex or +Inf if it is unreachable.
+	Distances [][]float64
+}
+
+func newMatrix(ids []int64) Matrix {
+	m := Matrix{
+		VertexIDs: ids,
+		Distances: make([][]float64, len(ids)),
+	}
+	for i := range m.Distances {
+		m.Distances[i] = make([]float64, len(ids))
+		for j := range m.Distances[i] {
+			if i != j {
+				m.Distances[i][j] = math.Inf(1)
+			}
+		}
+	}
+	return m
+}
+
+// DistanceMatrix builds graph from the given vertexes and edges and
+// returns distances between all pairs of its vertexes.
+func DistanceMatrix(vs []entity.Vertex, es []entity.Edge) (Matrix, error) {
+	return NewGraph(vs, es).DistanceMatrix()
+}
+
+// DistanceMatrix returns distances between all pairs of vertexes. Johnson
+// algorithm is used for sparse graphs and Floyd-Warshall for dense ones.
+func (g *Graph) DistanceMatrix() (Matrix, error) {
+	if g.edges < len(g.ids)*len(g.ids)/4 {
+		return g.Johnson()
+	}
+	return g.FloydWarshall()
+}
+
+// FloydWarshall returns distances between all pairs of vertexes computed
+// by Floyd-Warshall algorithm. NegativeCycleError is returned if the
+// graph has negative cycle.
+func (g *Graph) FloydWarshall() (Matrix, error) {
+	m := newMatrix(g.ids)
+	d := m.Distances
+
+	for v := range g.outEdges {
+		for _, e := range g.outEdges[v] {
+			if e.weight < d[v][e.to] {
+				d[v][e.to] = e.weight
+			}
+		}
+	}
+
+	for k := range d {
+		for i := range d {
+			if math.IsInf(d[i][k], 1) {
+				continue
+			}
+			for j := range d {
+				if dk := d[i][k] + d[k][j]; dk < d[i][j] {
+					d[i][j] = dk
+				}
+			}
+		}
+	}
+
+	for v := range d {
+		if d[v][v] < 0 {
+			if _, err := g.potentials(); err != nil {
+				return Matrix{}, err
+			}
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// Johnson returns distances between all pairs of vertexes computed by
+// Johnson algorithm. NegativeCycleError is returned if the graph has
+// negative cycle.
+func (g *Graph) Johnson() (Matrix, error) {
+	rg := g
+	var h []float64
+
+	if g.negative {
+		var err error
+		h, err = g.potentials()
+		if err != nil {
+			return Matrix{}, err
+		}
+
+		// Reweight edges so that they are non-negative and the shortest
+		// paths stay the same.
+		rg = &Graph{
+			ids:      g.ids,
+			index:    g.index,
+			vertexes: g.vertexes,
+			outEdges: make([][]edge, len(g.outEdges)),
+			edges:    g.edges,
+		}
+		for v := range g.outEdges {
+			rg.outEdges[v] = make([]edge, len(g.outEdges[v]))
+			for i, e := range g.outEdges[v] {
+				e.weight = math.Max(0, e.weight+h[e.from]-h[e.to])
+				rg.outEdges[v][i] = e
+			}
+		}
+	}
+
+	m := newMatrix(g.ids)
+
+	for src := range g.ids {
+		t := rg.dijkstra(src, -1, nil, nil)
+		for dst, d := range t.distances {
+			if h != nil && !math.IsInf(d, 1) {
+				d += h[dst] - h[src]
+			}
+			m.Distances[src][dst] = d
+		}
+	}
+
+	return m, nil
+}
+
+// potentials returns Johnson vertex potentials which are distances from
+// the virtual vertex connected to every vertex with zero weight edge.
+// NegativeCycleError is returned if the graph has negative cycle.
+func (g *Graph) potentials() ([]float64, error) {
+	h := make([]float64, len(g.ids))
+	prev := make([]*edge, len(g.ids))
+
+	relax := func() (relaxed bool) {
+		for v := range g.outEdges {
+			for i := range g.outEdges[v] {
+				e := &g.outEdges[v][i]
+				if d := h[v] + e.weight; d < h[e.to] {
+					h[e.to] = d
+					prev[e.to] = e
+					relaxed = true
+				}
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(g.ids); i++ {
+		if !relax() {
+			return h, nil
+		}
+	}
+
+	// Relaxations after |V| iterations mean there is a negative cycle
+	// which eventually shows up in prev.
+	for {
+		if v, found := prevCycle(prev); found {
+			return nil, &NegativeCycleError{
+				EdgeIDs: g.cycleEdgeIDs(prev, v),
+			}
+		}
+		relax()
+	}
+}
+
+// prevCycle returns a vertex lying on a cycle in prev if there is one.
+func prevCycle(prev []*edge) (int, bool) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	states := make([]int, len(prev))
+
+	for v := range prev {
+		u := v
+		for states[u] == unvisited {
+			states[u] = visiting
+			if prev[u] == nil {
+				break
+			}
+			u = prev[u].from
+		}
+		if states[u] == visiting && prev[u] != nil {
+			return u, true
+		}
+		for u := v; states[u] == visiting; {
+			states[u] = visited
+			if prev[u] == nil {
+				break
+			}
+			u = prev[u].from
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package dijkstra
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestDistanceMatrix(t *testing.T) {
+	vs := []entity.Vertex{{}, {ID: 1}, {ID: 2}}
+	es := []entity.Edge{
+		{ID: 0, From: 0, To: 1, Weight: 2},
+		{ID: 1, From: 1, To: 2, Weight: -1},
+		{ID: 2, From: 0, To: 2, Weight: 3},
+	}
+
+	inf := math.Inf(1)
+	want := [][]float64{
+		{0, 2, 1},
+		{inf, 0, -1},
+		{inf, inf, 0},
+	}
+
+	g := NewGraph(vs, es)
+
+	for name, f := range map[string]func() (Matrix, error){
+		"FloydWarshall":  g.FloydWarshall,
+		"Johnson":        g.Johnson,
+		"DistanceMatrix": g.DistanceMatrix,
+	} {
+		got, err := f()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		for i := range want {
+			for j := range want[i] {
+				if got.Distances[i][j] != want[i][j] {
+					t.Errorf("%s() distance from %d to %d = %v, want %v",
+						name, got.VertexIDs[i], got.VertexIDs[j],
+						got.Distances[i][j], want[i][j])
+				}
+			}
+		}
+	}
+}
+
+func TestDistanceMatrix_random(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+
+	for i := 0; i < 2000; i++ {
+		vs, es := randomGraph(r, 7, 20)
+		if r.Intn(2) == 0 {
+			for j := range es {
+				es[j].Weight -= 2
+			}
+		}
+
+		var negativeCycle bool
+		for _, v := range vs {
+			if hasNegativeCycle(vs, es, v.ID, v.ID) {
+				negativeCycle = true
+				break
+			}
+		}
+
+		g := NewGraph(vs, es)
+
+		fw, fwErr := g.FloydWarshall()
+		j, jErr := g.Johnson()
+
+		if negativeCycle {
+			for name, err := range map[string]error{
+				"FloydWarshall": fwErr,
+				"Johnson":       jErr,
+			} {
+				var cycleErr *NegativeCycleError
+				if !errors.As(err, &cycleErr) {
+					t.Fatalf("graph %d: %s() error = %v, "+
+						"want NegativeCycleError", i, name, err)
+				}
+				cost := cycleCost(t, es, cycleErr.EdgeIDs)
+				if cost >= 0 {
+					t.Fatalf("graph %d: %s() cycle cost = %v",
+						i, name, cost)
+				}
+			}
+			continue
+		}
+
+		if fwErr != nil || jErr != nil {
+			t.Fatalf("graph %d: FloydWarshall() error = %v, "+
+				"Johnson() error = %v", i, fwErr, jErr)
+		}
+
+		for a, from := range fw.VertexIDs {
+			for b, to := range fw.VertexIDs {
+				want, connected := 0.0, true
+				if from != to {
+					want, connected = bruteForceDistance(vs, es, from, to)
+				}
+				if !connected {
+					want = math.Inf(1)
+				}
+				for name, got := range map[string]float64{
+					"FloydWarshall": fw.Distances[a][b],
+					"Johnson":       j.Distances[a][b],
+				} {
+					if got != want && math.Abs(got-want) > 1e-9 {
+						t.Fatalf("graph %d: %s() distance from %d to %d "+
+							"= %v, want %v", i, name, from, to, got, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package dijkstra
+
+// Path is a path in the graph.
+type Path struct {
+	EdgeIDs   []int64 `json:"edge_ids"`
+	VertexIDs []int64 `json:"vertex_ids"`
+	TotalCost float64 `json:"total_cost"`
+	Hops      int     `json:"hops"`
+
+	// Explored is number of vertexes explored while searching the path.
+	Explored int `json:"explored,omitempty"`
+}
+
+func (g *Graph) newPath(src int, edges []*edge) Path {
+	p := Path{
+		EdgeIDs:   edgeIDs(edges),
+		VertexIDs: make([]int64, 0, len(edges)+1),
+		Hops:      len(edges),
+	}
+
+	p.VertexIDs = append(p.VertexIDs, g.ids[src])
+	for _, e := range edges {
+		p.VertexIDs = append(p.VertexIDs, g.ids[e.to])
+		p.TotalCost += e.weight
+	}
+
+	return p
+}
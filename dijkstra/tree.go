@@ -0,0 +1,67 @@
+package dijkstra
+
+import (
+	"github.com/dimuls/graph/entity"
+)
+
+// Tree is the shortest path tree from the source vertex.
+type Tree struct {
+	// Distances maps reachable vertex IDs to their distances from the
+	// source vertex. Unreachable vertexes are absent.
+	Distances map[int64]float64
+
+	// PrevEdgeIDs maps reachable vertex IDs except the source vertex to
+	// IDs of the last edges on the shortest paths to them.
+	PrevEdgeIDs map[int64]int64
+}
+
+// ShortestPathTree builds graph from the given vertexes and edges and
+// returns the shortest path tree from the from vertex.
+func ShortestPathTree(vs []entity.Vertex, es []entity.Edge,
+	from int64) (Tree, error) {
+
+	return NewGraph(vs, es).ShortestPathTree(from)
+}
+
+// ShortestPathTree returns the shortest path tree from the from vertex.
+// NegativeCycleError is returned if negative cycle is reachable from it.
+func (g *Graph) ShortestPathTree(from int64) (Tree, error) {
+	src, exists := g.index[from]
+	if !exists {
+		return Tree{}, entity.ErrVertexNotFound
+	}
+
+	var t *tree
+	if g.negative {
+		t = g.bellmanFord(src, nil)
+	} else {
+		t = g.dijkstra(src, -1, nil, nil)
+	}
+
+	if t.cycles != nil {
+		for v := range t.cycles {
+			if t.cycles[v] == v {
+				return Tree{}, &NegativeCycleError{
+					EdgeIDs: g.cycleEdgeIDs(t.prev, v),
+				}
+			}
+		}
+	}
+
+	res := Tree{
+		Distances:   make(map[int64]float64, t.explored),
+		PrevEdgeIDs: make(map[int64]int64, t.explored),
+	}
+
+	for v, id := range g.ids {
+		if !t.reachable(v) {
+			continue
+		}
+		res.Distances[id] = t.distances[v]
+		if t.prev[v] != nil {
+			res.PrevEdgeIDs[id] = t.prev[v].id
+		}
+	}
+
+	return res, nil
+}
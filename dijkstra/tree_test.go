@@ -0,0 +1,94 @@
+package dijkstra
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestShortestPathTree(t *testing.T) {
+	vs := []entity.Vertex{{}, {ID: 1}, {ID: 2}, {ID: 3}}
+	es := []entity.Edge{
+		{ID: 0, From: 0, To: 1, Weight: 1},
+		{ID: 1, From: 1, To: 2, Weight: 1},
+		{ID: 2, From: 0, To: 2, Weight: 3},
+		{ID: 3, From: 3, To: 0, Weight: 1},
+	}
+
+	got, err := ShortestPathTree(vs, es, 0)
+	if err != nil {
+		t.Fatalf("ShortestPathTree() error = %v", err)
+	}
+
+	want := Tree{
+		Distances:   map[int64]float64{0: 0, 1: 1, 2: 2},
+		PrevEdgeIDs: map[int64]int64{1: 0, 2: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ShortestPathTree() got = %+v, want %+v", got, want)
+	}
+
+	es = append(es, entity.Edge{ID: 4, From: 2, To: 1, Weight: -2})
+
+	_, err = ShortestPathTree(vs, es, 0)
+
+	var cycleErr *NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("ShortestPathTree() error = %v, want NegativeCycleError",
+			err)
+	}
+}
+
+func TestShortestPathTree_random(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+
+	for i := 0; i < 2000; i++ {
+		vs, es := randomGraph(r, 7, 15)
+		from := vs[r.Intn(len(vs))].ID
+
+		got, err := ShortestPathTree(vs, es, from)
+		if err != nil {
+			t.Fatalf("graph %d: ShortestPathTree() error = %v", i, err)
+		}
+
+		for _, v := range vs {
+			d, reachable := got.Distances[v.ID]
+
+			if v.ID == from {
+				if !reachable || d != 0 {
+					t.Fatalf("graph %d: source distance = %v, %v",
+						i, d, reachable)
+				}
+				continue
+			}
+
+			want, connected := bruteForceDistance(vs, es, from, v.ID)
+			if reachable != connected {
+				t.Fatalf("graph %d: vertex %d reachable = %v, want %v",
+					i, v.ID, reachable, connected)
+			}
+			if !reachable {
+				continue
+			}
+			if math.Abs(d-want) > 1e-9 {
+				t.Fatalf("graph %d: vertex %d distance = %v, want %v",
+					i, v.ID, d, want)
+			}
+
+			e := es[got.PrevEdgeIDs[v.ID]]
+			prev, to := e.From, e.To
+			if e.Undirected && to != v.ID {
+				prev, to = to, prev
+			}
+			if to != v.ID ||
+				math.Abs(got.Distances[prev]+e.Weight-d) > 1e-9 {
+				t.Fatalf("graph %d: vertex %d has wrong previous edge %+v",
+					i, v.ID, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package dijkstra
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/dimuls/graph/entity"
+)
+
+var ErrInvalidK = errors.New("invalid k")
+
+// KShortestPaths builds graph from the given vertexes and edges and
+// returns up to k shortest loopless paths between from and to vertexes.
+func KShortestPaths(vs []entity.Vertex, es []entity.Edge,
+	from int64, to int64, k int) ([]Path, error) {
+
+	return NewGraph(vs, es).KShortestPaths(from, to, k)
+}
+
+// candidate is a path found by Yen's algorithm.
+type candidate struct {
+	edges []*edge
+	cost  float64
+}
+
+func newCandidate(edges []*edge) candidate {
+	c := candidate{edges: edges}
+	for _, e := range edges {
+		c.cost += e.weight
+	}
+	return c
+}
+
+// less orders candidates by cost and then by edge IDs.
+func (c candidate) less(o candidate) bool {
+	if c.cost != o.cost {
+		return c.cost < o.cost
+	}
+	for i := 0; i < len(c.edges) && i < len(o.edges); i++ {
+		if c.edges[i].id != o.edges[i].id {
+			return c.edges[i].id < o.edges[i].id
+		}
+	}
+	return len(c.edges) < len(o.edges)
+}
+
+func (c candidate) key() string {
+	key := make([]byte, 0, 8*len(c.edges))
+	for _, e := range c.edges {
+		id := uint64(e.id)
+		for i := 0; i < 8; i++ {
+			key = append(key, byte(id>>(8*i)))
+		}
+	}
+	return string(key)
+}
+
+// KShortestPaths returns up to k shortest loopless paths between from and
+// to vertexes found by Yen's algorithm. Paths are ordered by cost, paths
+// with the same cost are ordered by edge IDs.
+func (g *Graph) KShortestPaths(from int64, to int64, k int) ([]Path,
+	error) {
+
+	if k < 1 {
+		return nil, ErrInvalidK
+	}
+
+	src, exists := g.index[from]
+	if !exists {
+		return nil, entity.ErrVertexNotFound
+	}
+
+	dst, exists := g.index[to]
+	if !exists {
+		return nil, entity.ErrVertexNotFound
+	}
+
+	if src == dst {
+		return []Path{g.newPath(src, nil)}, nil
+	}
+
+	first, _, err := g.route(src, dst, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []candidate{newCandidate(first)}
+	seen := map[string]bool{found[0].key(): true}
+
+	var candidates []candidate
+
+	m := &mask{
+		vertexes: make([]bool, len(g.ids)),
+		edges:    map[*edge]bool{},
+	}
+
+	for len(found) < k {
+		last := found[len(found)-1].edges
+
+		for i := range last {
+			spur := src
+			if i > 0 {
+				spur = last[i-1].to
+			}
+			root := last[:i]
+
+			for v := range m.vertexes {
+				m.vertexes[v] = false
+			}
+			for e := range m.edges {
+				delete(m.edges, e)
+			}
+
+			for _, c := range found {
+				if len(c.edges) > i && sameEdges(c.edges[:i], root) {
+					m.edges[c.edges[i]] = true
+				}
+			}
+			for _, e := range root {
+				m.vertexes[e.from] = true
+			}
+
+			spurPath, _, err := g.route(spur, dst, nil, m)
+			if err != nil {
+				continue
+			}
+
+			edges := make([]*edge, 0, len(root)+len(spurPath))
+			edges = append(edges, root...)
+			edges = append(edges, spurPath...)
+
+			c := newCandidate(edges)
+			if key := c.key(); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, c)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].less(candidates[j])
+		})
+
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	paths := make([]Path, len(found))
+	for i, c := range found {
+		paths[i] = g.newPath(src, c.edges)
+	}
+
+	return paths, nil
+}
+
+func sameEdges(a, b []*edge) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package dijkstra
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestKShortestPaths(t *testing.T) {
+	vs := []entity.Vertex{{}, {ID: 1}, {ID: 2}, {ID: 3}}
+	es := []entity.Edge{
+		{ID: 0, From: 0, To: 1, Weight: 1},
+		{ID: 1, From: 1, To: 3, Weight: 1},
+		{ID: 2, From: 0, To: 2, Weight: 1},
+		{ID: 3, From: 2, To: 3, Weight: 1},
+		{ID: 4, From: 0, To: 3, Weight: 3},
+		{ID: 5, From: 1, To: 2, Weight: 1},
+	}
+
+	got, err := KShortestPaths(vs, es, 0, 3, 10)
+	if err != nil {
+		t.Fatalf("KShortestPaths() error = %v", err)
+	}
+
+	want := []Path{
+		{EdgeIDs: []int64{0, 1}, VertexIDs: []int64{0, 1, 3},
+			TotalCost: 2, Hops: 2},
+		{EdgeIDs: []int64{2, 3}, VertexIDs: []int64{0, 2, 3},
+			TotalCost: 2, Hops: 2},
+		{EdgeIDs: []int64{0, 5, 3}, VertexIDs: []int64{0, 1, 2, 3},
+			TotalCost: 3, Hops: 3},
+		{EdgeIDs: []int64{4}, VertexIDs: []int64{0, 3},
+			TotalCost: 3, Hops: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KShortestPaths() got = %v, want %v", got, want)
+	}
+
+	_, err = KShortestPaths(vs, es, 0, 3, 0)
+	if err != ErrInvalidK {
+		t.Errorf("KShortestPaths() error = %v, want %v", err, ErrInvalidK)
+	}
+}
+
+// allSimplePathCosts returns sorted costs of all loopless paths between
+// from and to vertexes.
+func allSimplePathCosts(es []entity.Edge, from int64, to int64) []float64 {
+	es = arcs(es)
+
+	var costs []float64
+	visited := map[int64]bool{from: true}
+
+	var walk func(v int64, cost float64)
+	walk = func(v int64, cost float64) {
+		if v == to {
+			costs = append(costs, cost)
+			return
+		}
+		for _, e := range es {
+			if e.From != v || visited[e.To] {
+				continue
+			}
+			visited[e.To] = true
+			walk(e.To, cost+e.Weight)
+			visited[e.To] = false
+		}
+	}
+
+	walk(from, 0)
+
+	sort.Float64s(costs)
+
+	return costs
+}
+
+func TestKShortestPaths_random(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+
+	for i := 0; i < 3000; i++ {
+		vs, es := randomGraph(r, 6, 14)
+		for j := range es {
+			es[j].Weight = float64(r.Intn(5))
+		}
+
+		from := vs[r.Intn(len(vs))].ID
+		to := vs[r.Intn(len(vs))].ID
+		if from == to {
+			continue
+		}
+
+		k := 1 + r.Intn(10)
+
+		got, err := KShortestPaths(vs, es, from, to, k)
+
+		want := allSimplePathCosts(es, from, to)
+		if len(want) == 0 {
+			if err != ErrNotConnected {
+				t.Fatalf("graph %d: KShortestPaths() error = %v, want %v",
+					i, err, ErrNotConnected)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("graph %d: KShortestPaths() error = %v", i, err)
+		}
+
+		if len(want) > k {
+			want = want[:k]
+		}
+		if len(got) != len(want) {
+			t.Fatalf("graph %d: KShortestPaths() returned %d paths, "+
+				"want %d", i, len(got), len(want))
+		}
+
+		seen := map[string]bool{}
+		for j, p := range got {
+			if cost := pathCost(t, es, p.EdgeIDs, from, to); cost != p.TotalCost {
+				t.Fatalf("graph %d: path %d cost = %v, total cost = %v",
+					i, j, cost, p.TotalCost)
+			}
+			if p.TotalCost != want[j] {
+				t.Fatalf("graph %d: path %d cost = %v, want %v",
+					i, j, p.TotalCost, want[j])
+			}
+			vertexes := map[int64]bool{}
+			for _, id := range p.VertexIDs {
+				if vertexes[id] {
+					t.Fatalf("graph %d: path %v has loop", i, p.EdgeIDs)
+				}
+				vertexes[id] = true
+			}
+			key := fmt.Sprint(p.EdgeIDs)
+			if seen[key] {
+				t.Fatalf("graph %d: path %v is duplicated", i, p.EdgeIDs)
+			}
+			seen[key] = true
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Change is the change log record of graph, vertex or edge mutation.
+type Change struct {
+	ID        int64           `json:"id" db:"id"`
+	GraphID   int64           `json:"graph_id" db:"graph_id"`
+	Entity    string          `json:"entity" db:"entity"`
+	EntityID  int64           `json:"entity_id" db:"entity_id"`
+	Operation string          `json:"operation" db:"operation"`
+	Before    json.RawMessage `json:"before" db:"before"`
+	After     json.RawMessage `json:"after" db:"after"`
+	Actor     string          `json:"actor" db:"actor"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	TxID      int64           `json:"tx_id" db:"tx_id"`
+}
+
+// Change entities.
+const (
+	EntityGraph  = "graph"
+	EntityVertex = "vertex"
+	EntityEdge   = "edge"
+)
+
+// Change operations.
+const (
+	OperationInsert = "insert"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// ChangeFilter selects change log records. Zero fields do not filter.
+type ChangeFilter struct {
+	Entity   string
+	EntityID int64
+	Since    time.Time
+	Until    time.Time
+
+	// BeforeID selects records older than the record with the given ID
+	// and is used for pagination.
+	BeforeID int64
+	Limit    int
+}
@@ -1,21 +1,85 @@
 package entity
 
+import "encoding/json"
+
 type Graph struct {
-	ID   int64  `json:"id" db:"id"`
-	Name string `json:"name" db:"name"`
+	ID       int64  `json:"id" db:"id"`
+	Name     string `json:"name" db:"name"`
+	Directed bool   `json:"directed" db:"directed"`
+	Version  int64  `json:"version" db:"version"`
 }
 
 type Vertex struct {
-	ID      int64   `json:"id" db:"id"`
-	GraphID int64   `json:"graph_id" db:"graph_id"`
-	X       float64 `json:"x" db:"x"`
-	Y       float64 `json:"y" db:"y"`
+	ID         int64      `json:"id" db:"id"`
+	GraphID    int64      `json:"graph_id" db:"graph_id"`
+	X          float64    `json:"x" db:"x"`
+	Y          float64    `json:"y" db:"y"`
+	Label      string     `json:"label" db:"label"`
+	Attributes Attributes `json:"attributes" db:"attributes"`
+	Version    int64      `json:"version" db:"version"`
 }
 
+// Edge is directed unless Undirected is set. It is stored and encoded to
+// JSON with directed flag.
 type Edge struct {
-	ID      int64   `json:"id" db:"id"`
-	GraphID int64   `json:"graph_id" db:"graph_id"`
-	From    int64   `json:"from" db:"from"`
-	To      int64   `json:"to" db:"to"`
-	Weight  float64 `json:"weight" db:"weight"`
+	ID         int64      `json:"id" db:"id"`
+	GraphID    int64      `json:"graph_id" db:"graph_id"`
+	From       int64      `json:"from" db:"from"`
+	To         int64      `json:"to" db:"to"`
+	Weight     float64    `json:"weight" db:"weight"`
+	Undirected bool       `json:"-" db:"undirected"`
+	Label      string     `json:"label" db:"label"`
+	Attributes Attributes `json:"attributes" db:"attributes"`
+	Version    int64      `json:"version" db:"version"`
+}
+
+// edgeJSON is Edge as it is encoded to JSON.
+type edgeJSON struct {
+	ID         int64      `json:"id"`
+	GraphID    int64      `json:"graph_id"`
+	From       int64      `json:"from"`
+	To         int64      `json:"to"`
+	Weight     float64    `json:"weight"`
+	Directed   *bool      `json:"directed"`
+	Label      string     `json:"label"`
+	Attributes Attributes `json:"attributes"`
+	Version    int64      `json:"version"`
+}
+
+func (e Edge) MarshalJSON() ([]byte, error) {
+	directed := !e.Undirected
+	return json.Marshal(edgeJSON{
+		ID:         e.ID,
+		GraphID:    e.GraphID,
+		From:       e.From,
+		To:         e.To,
+		Weight:     e.Weight,
+		Directed:   &directed,
+		Label:      e.Label,
+		Attributes: e.Attributes,
+		Version:    e.Version,
+	})
+}
+
+// UnmarshalJSON decodes the edge. Edge without directed flag is directed.
+func (e *Edge) UnmarshalJSON(data []byte) error {
+	var je edgeJSON
+	err := json.Unmarshal(data, &je)
+	if err != nil {
+		return err
+	}
+
+	*e = Edge{
+		ID:         je.ID,
+		GraphID:    je.GraphID,
+		From:       je.From,
+		To:         je.To,
+		Weight:     je.Weight,
+		Undirected: je.Directed != nil && !*je.Directed,
+		Label:      je.Label,
+		Attributes: je.Attributes,
+		Version:    je.Version,
+	}
+
+	return nil
 }
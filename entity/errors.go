@@ -9,4 +9,12 @@ var (
 	ErrVertexNotFound = errors.New("vertex not found")
 
 	ErrEdgeNotFound = errors.New("edge not found")
+
+	ErrVersionConflict = errors.New("version conflict")
+
+	ErrSnapshotNotFound       = errors.New("snapshot not found")
+	ErrDuplicatedSnapshotName = errors.New("duplicated snapshot name")
+
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
 )
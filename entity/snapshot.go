@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Snapshot is the saved state of graph vertexes and edges.
+type Snapshot struct {
+	ID          int64     `json:"id" db:"id"`
+	GraphID     int64     `json:"graph_id" db:"graph_id"`
+	Name        string    `json:"name" db:"name"`
+	VertexCount int       `json:"vertex_count" db:"vertex_count"`
+	EdgeCount   int       `json:"edge_count" db:"edge_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
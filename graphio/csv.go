@@ -0,0 +1,314 @@
+package graphio
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// Names of CSV files, they are also used in zip archives.
+const (
+	VertexesCSV = "vertices.csv"
+	EdgesCSV    = "edges.csv"
+)
+
+// maxCSVErrors limits the number of errors collected while reading CSV.
+const maxCSVErrors = 100
+
+// CSVRowError is the error in the row of CSV file.
+type CSVRowError struct {
+	File    string `json:"file"`
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (e CSVRowError) Error() string {
+	return fmt.Sprintf("%s: row %d: %s", e.File, e.Row, e.Message)
+}
+
+// CSVError lists errors in rows of CSV files.
+type CSVError struct {
+	Rows []CSVRowError
+}
+
+func (e *CSVError) Error() string {
+	msg := e.Rows[0].Error()
+	if len(e.Rows) > 1 {
+		msg += fmt.Sprintf(" and %d more errors", len(e.Rows)-1)
+	}
+	return msg
+}
+
+func (e *CSVError) add(file string, row int, format string,
+	args ...interface{}) {
+
+	if len(e.Rows) < maxCSVErrors {
+		e.Rows = append(e.Rows, CSVRowError{
+			File:    file,
+			Row:     row,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+}
+
+// ReadCSV reads the graph from vertexes CSV with id,x,y[,label] columns
+// and edges CSV with from,to,weight[,directed[,label]] columns. Vertex IDs
+// are arbitrary strings which only link edges with vertexes. The first row
+// is skipped if it is a header. Edges are directed unless the directed
+// column is false. Invalid rows are reported with *CSVError.
+func ReadCSV(vertexes io.Reader, edges io.Reader) (Graph, error) {
+	var (
+		g    = Graph{Graph: entity.Graph{Directed: true}}
+		cerr = &CSVError{}
+
+		// ids maps CSV vertex IDs to local IDs and rows maps them to rows
+		// where they are defined.
+		ids  = map[string]int64{}
+		rows = map[string]int{}
+	)
+
+	err := readCSV(vertexes, "id", func(row int, rec []string) {
+		if len(rec) < 3 || len(rec) > 4 {
+			cerr.add(VertexesCSV, row, "expected 3 or 4 columns, got %d",
+				len(rec))
+			return
+		}
+
+		id := strings.TrimSpace(rec[0])
+		if id == "" {
+			cerr.add(VertexesCSV, row, "empty vertex id")
+			return
+		}
+		if first, exists := rows[id]; exists {
+			cerr.add(VertexesCSV, row, "duplicated vertex %s, first "+
+				"defined in row %d", id, first)
+			return
+		}
+
+		x, err := parseFloat(strings.TrimSpace(rec[1]))
+		if err != nil {
+			cerr.add(VertexesCSV, row, "invalid x %q", rec[1])
+			return
+		}
+
+		y, err := parseFloat(strings.TrimSpace(rec[2]))
+		if err != nil {
+			cerr.add(VertexesCSV, row, "invalid y %q", rec[2])
+			return
+		}
+
+		v := entity.Vertex{ID: int64(len(g.Vertexes) + 1), X: x, Y: y}
+		if len(rec) == 4 {
+			v.Label = rec[3]
+		}
+
+		ids[id] = v.ID
+		rows[id] = row
+		g.Vertexes = append(g.Vertexes, v)
+	})
+	if err != nil {
+		return Graph{}, fmt.Errorf("read %s: %w", VertexesCSV, err)
+	}
+
+	err = readCSV(edges, "from", func(row int, rec []string) {
+		if len(rec) < 3 || len(rec) > 5 {
+			cerr.add(EdgesCSV, row, "expected 3 to 5 columns, got %d",
+				len(rec))
+			return
+		}
+
+		fromID, toID := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])
+
+		from, exists := ids[fromID]
+		if !exists {
+			cerr.add(EdgesCSV, row, "unknown vertex %s in from", fromID)
+			return
+		}
+
+		to, exists := ids[toID]
+		if !exists {
+			cerr.add(EdgesCSV, row, "unknown vertex %s in to", toID)
+			return
+		}
+
+		weight, err := parseFloat(strings.TrimSpace(rec[2]))
+		if err != nil {
+			cerr.add(EdgesCSV, row, "invalid weight %q", rec[2])
+			return
+		}
+
+		e := entity.Edge{
+			ID:     int64(len(g.Edges) + 1),
+			From:   from,
+			To:     to,
+			Weight: weight,
+		}
+
+		if len(rec) > 3 && strings.TrimSpace(rec[3]) != "" {
+			directed, err := strconv.ParseBool(strings.TrimSpace(rec[3]))
+			if err != nil {
+				cerr.add(EdgesCSV, row, "invalid directed %q", rec[3])
+				return
+			}
+			e.Undirected = !directed
+		}
+		if len(rec) > 4 {
+			e.Label = rec[4]
+		}
+
+		g.Edges = append(g.Edges, e)
+	})
+	if err != nil {
+		return Graph{}, fmt.Errorf("read %s: %w", EdgesCSV, err)
+	}
+
+	if len(cerr.Rows) > 0 {
+		return Graph{}, cerr
+	}
+
+	return g, nil
+}
+
+// readCSV calls f with every record of CSV and its row number. The first
+// record is skipped if its first field is the header.
+func readCSV(r io.Reader, header string,
+	f func(row int, rec []string)) error {
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			// Spreadsheets may start files with byte order mark.
+			rec[0] = strings.TrimPrefix(rec[0], "\ufeff")
+			if strings.EqualFold(strings.TrimSpace(rec[0]), header) {
+				continue
+			}
+		}
+
+		row, _ := cr.FieldPos(0)
+		f(row, rec)
+	}
+}
+
+// ReadCSVZip reads the graph from the zip archive with vertices.csv and
+// edges.csv files like ReadCSV.
+func ReadCSVZip(r io.ReaderAt, size int64) (Graph, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return Graph{}, fmt.Errorf("open zip: %w", err)
+	}
+
+	var files [2]io.ReadCloser
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+
+	for i, name := range []string{VertexesCSV, EdgesCSV} {
+		files[i], err = zr.Open(name)
+		if err != nil {
+			return Graph{}, fmt.Errorf("open %s: %w", name, err)
+		}
+	}
+
+	return ReadCSV(files[0], files[1])
+}
+
+// WriteCSV writes graph vertexes and edges in the format read by ReadCSV.
+func WriteCSV(vertexes io.Writer, edges io.Writer, g Graph) error {
+	vw := csv.NewWriter(vertexes)
+
+	err := vw.Write([]string{"id", "x", "y", "label"})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range g.Vertexes {
+		err = vw.Write([]string{
+			strconv.FormatInt(v.ID, 10),
+			formatFloat(v.X),
+			formatFloat(v.Y),
+			v.Label,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	vw.Flush()
+	if err = vw.Error(); err != nil {
+		return err
+	}
+
+	ew := csv.NewWriter(edges)
+
+	err = ew.Write([]string{"from", "to", "weight", "directed", "label"})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range g.Edges {
+		err = ew.Write([]string{
+			strconv.FormatInt(e.From, 10),
+			strconv.FormatInt(e.To, 10),
+			formatFloat(e.Weight),
+			strconv.FormatBool(!e.Undirected),
+			e.Label,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ew.Flush()
+	return ew.Error()
+}
+
+// WriteCSVZip writes the zip archive with vertices.csv and edges.csv files.
+func WriteCSVZip(w io.Writer, g Graph) error {
+	zw := zip.NewWriter(w)
+
+	vw, err := zw.Create(VertexesCSV)
+	if err != nil {
+		return err
+	}
+
+	// Files of the archive are written one by one, so edges are buffered
+	// until vertexes are written.
+	var edges strings.Builder
+
+	err = WriteCSV(vw, &edges, g)
+	if err != nil {
+		return err
+	}
+
+	ew, err := zw.Create(EdgesCSV)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(ew, edges.String())
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,93 @@
+package graphio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestReadCSV(t *testing.T) {
+	vertexes := "\ufeffid,x,y,label\na,0,1.5,A\nb,2,3\n"
+	edges := "a,b,2\nb,a,-1,false,back\n"
+
+	g, err := ReadCSV(strings.NewReader(vertexes), strings.NewReader(edges))
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+
+	want := Graph{
+		Graph: entity.Graph{Directed: true},
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 0, Y: 1.5, Label: "A"},
+			{ID: 2, X: 2, Y: 3},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 2},
+			{ID: 2, From: 2, To: 1, Weight: -1, Undirected: true,
+				Label: "back"},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadCSV() got = %+v, want %+v", g, want)
+	}
+}
+
+func TestReadCSV_errors(t *testing.T) {
+	vertexes := "id,x,y\na,0,0\na,1,1\nb,x,0\nc,NaN,0\ne,0,-Inf\n"
+	edges := "from,to,weight\na,a,1\na,c,1\nd,a,1\na,a,w\na,a,inf\n"
+
+	_, err := ReadCSV(strings.NewReader(vertexes), strings.NewReader(edges))
+
+	var cerr *CSVError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("ReadCSV() error = %v, want CSVError", err)
+	}
+
+	want := []CSVRowError{
+		{VertexesCSV, 3, "duplicated vertex a, first defined in row 2"},
+		{VertexesCSV, 4, `invalid x "x"`},
+		{VertexesCSV, 5, `invalid x "NaN"`},
+		{VertexesCSV, 6, `invalid y "-Inf"`},
+		{EdgesCSV, 3, "unknown vertex c in to"},
+		{EdgesCSV, 4, "unknown vertex d in from"},
+		{EdgesCSV, 5, `invalid weight "w"`},
+		{EdgesCSV, 6, `invalid weight "inf"`},
+	}
+	if !reflect.DeepEqual(cerr.Rows, want) {
+		t.Errorf("ReadCSV() errors = %+v, want %+v", cerr.Rows, want)
+	}
+}
+
+func TestCSVZip(t *testing.T) {
+	g := Graph{
+		Graph: entity.Graph{Directed: true},
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 0.1, Y: -2, Label: "a, \"b\""},
+			{ID: 2, X: 1e10, Y: 3},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 0.3},
+			{ID: 2, From: 2, To: 2, Weight: -1, Undirected: true,
+				Label: "loop"},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteCSVZip(&buf, g)
+	if err != nil {
+		t.Fatalf("WriteCSVZip() error = %v", err)
+	}
+
+	got, err := ReadCSVZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadCSVZip() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, g) {
+		t.Errorf("ReadCSVZip() got = %+v, want %+v", got, g)
+	}
+}
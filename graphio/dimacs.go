@@ -0,0 +1,197 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// Limits of problem line counts. The largest graph of the challenge, the
+// full USA, has about 24 million nodes and 58 million arcs.
+const (
+	maxDIMACSNodes = 1 << 25
+	maxDIMACSArcs  = 1 << 26
+)
+
+// DIMACSQuery is the point to point query of 9th DIMACS challenge.
+type DIMACSQuery struct {
+	From int64
+	To   int64
+}
+
+// dimacsLines calls f with fields of every not empty and not comment line
+// of DIMACS file. Errors are prefixed with the line number.
+func dimacsLines(r io.Reader, f func(fields []string) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		err := f(fields)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	return s.Err()
+}
+
+// parseDIMACSInts parses fields as integers.
+func parseDIMACSInts(fields []string) ([]int64, error) {
+	ns := make([]int64, len(fields))
+	for i, f := range fields {
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		ns[i] = n
+	}
+	return ns, nil
+}
+
+// ReadDIMACS reads the graph of 9th DIMACS challenge from .gr file with
+// arcs and optional .co file with coordinates, co may be nil. Vertex IDs
+// are DIMACS node IDs, edges are directed and numbered from 1 in the
+// order of arcs.
+func ReadDIMACS(gr io.Reader, co io.Reader) ([]entity.Vertex, []entity.Edge,
+	error) {
+
+	var (
+		vs []entity.Vertex
+		es []entity.Edge
+	)
+
+	err := dimacsLines(gr, func(fields []string) error {
+		switch fields[0] {
+		case "p":
+			if len(fields) != 4 || fields[1] != "sp" {
+				return fmt.Errorf("invalid problem line")
+			}
+			ns, err := parseDIMACSInts(fields[2:])
+			if err != nil {
+				return err
+			}
+			if vs != nil {
+				return fmt.Errorf("duplicated problem line")
+			}
+			if ns[0] < 0 || ns[0] > maxDIMACSNodes {
+				return fmt.Errorf("invalid node count %d", ns[0])
+			}
+			if ns[1] < 0 || ns[1] > maxDIMACSArcs {
+				return fmt.Errorf("invalid arc count %d", ns[1])
+			}
+			vs = make([]entity.Vertex, ns[0])
+			for i := range vs {
+				vs[i].ID = int64(i + 1)
+			}
+			es = make([]entity.Edge, 0, ns[1])
+		case "a":
+			if vs == nil {
+				return fmt.Errorf("arc before problem line")
+			}
+			if len(fields) != 4 {
+				return fmt.Errorf("invalid arc line")
+			}
+			ns, err := parseDIMACSInts(fields[1:])
+			if err != nil {
+				return err
+			}
+			for _, id := range ns[:2] {
+				if id < 1 || id > int64(len(vs)) {
+					return fmt.Errorf("unknown node %d", id)
+				}
+			}
+			es = append(es, entity.Edge{
+				ID:     int64(len(es) + 1),
+				From:   ns[0],
+				To:     ns[1],
+				Weight: float64(ns[2]),
+			})
+		default:
+			return fmt.Errorf("unknown line type %q", fields[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read graph: %w", err)
+	}
+
+	if vs == nil {
+		return nil, nil, fmt.Errorf("read graph: no problem line")
+	}
+
+	if co == nil {
+		return vs, es, nil
+	}
+
+	err = dimacsLines(co, func(fields []string) error {
+		switch fields[0] {
+		case "p":
+		case "v":
+			if len(fields) != 4 {
+				return fmt.Errorf("invalid coordinates line")
+			}
+			ns, err := parseDIMACSInts(fields[1:])
+			if err != nil {
+				return err
+			}
+			if ns[0] < 1 || ns[0] > int64(len(vs)) {
+				return fmt.Errorf("unknown node %d", ns[0])
+			}
+			vs[ns[0]-1].X, vs[ns[0]-1].Y = float64(ns[1]), float64(ns[2])
+		default:
+			return fmt.Errorf("unknown line type %q", fields[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read coordinates: %w", err)
+	}
+
+	return vs, es, nil
+}
+
+// ReadDIMACSQueries reads point to point queries from .p2p file or single
+// source queries from .ss file. To of single source queries is zero.
+func ReadDIMACSQueries(r io.Reader) ([]DIMACSQuery, error) {
+	var qs []DIMACSQuery
+
+	err := dimacsLines(r, func(fields []string) error {
+		switch fields[0] {
+		case "p":
+		case "q":
+			if len(fields) != 3 {
+				return fmt.Errorf("invalid query line")
+			}
+			ns, err := parseDIMACSInts(fields[1:])
+			if err != nil {
+				return err
+			}
+			qs = append(qs, DIMACSQuery{From: ns[0], To: ns[1]})
+		case "s":
+			if len(fields) != 2 {
+				return fmt.Errorf("invalid source line")
+			}
+			ns, err := parseDIMACSInts(fields[1:])
+			if err != nil {
+				return err
+			}
+			qs = append(qs, DIMACSQuery{From: ns[0]})
+		default:
+			return fmt.Errorf("unknown line type %q", fields[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read queries: %w", err)
+	}
+
+	return qs, nil
+}
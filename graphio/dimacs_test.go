@@ -0,0 +1,78 @@
+package graphio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestReadDIMACS(t *testing.T) {
+	const gr = `c 9th DIMACS Implementation Challenge
+p sp 3 2
+c arcs
+a 1 2 10
+a 2 3 7
+`
+	const co = `p aux sp co 3
+v 1 -73530767 41085396
+v 2 -73530538 41086098
+v 3 -73519366 41048796
+`
+
+	vs, es, err := ReadDIMACS(strings.NewReader(gr), strings.NewReader(co))
+	if err != nil {
+		t.Fatalf("ReadDIMACS() error = %v", err)
+	}
+
+	wantVs := []entity.Vertex{
+		{ID: 1, X: -73530767, Y: 41085396},
+		{ID: 2, X: -73530538, Y: 41086098},
+		{ID: 3, X: -73519366, Y: 41048796},
+	}
+	if !reflect.DeepEqual(vs, wantVs) {
+		t.Errorf("ReadDIMACS() vertexes = %+v, want %+v", vs, wantVs)
+	}
+
+	wantEs := []entity.Edge{
+		{ID: 1, From: 1, To: 2, Weight: 10},
+		{ID: 2, From: 2, To: 3, Weight: 7},
+	}
+	if !reflect.DeepEqual(es, wantEs) {
+		t.Errorf("ReadDIMACS() edges = %+v, want %+v", es, wantEs)
+	}
+
+	_, _, err = ReadDIMACS(strings.NewReader("p sp 2 1\na 1 3 1\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadDIMACS() error = %v, want error in line 2", err)
+	}
+}
+
+func TestReadDIMACS_invalidCounts(t *testing.T) {
+	for _, p := range []string{
+		"p sp 2 -1",
+		"p sp -2 1",
+		"p sp 9223372036854775807 1",
+		"p sp 2 9223372036854775807",
+	} {
+		_, _, err := ReadDIMACS(strings.NewReader(p+"\n"), nil)
+		if err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ReadDIMACS(%q) error = %v, want error in line 1",
+				p, err)
+		}
+	}
+}
+
+func TestReadDIMACSQueries(t *testing.T) {
+	qs, err := ReadDIMACSQueries(strings.NewReader(
+		"p aux sp p2p 2\nq 1 2\nq 3 1\n"))
+	if err != nil {
+		t.Fatalf("ReadDIMACSQueries() error = %v", err)
+	}
+
+	want := []DIMACSQuery{{From: 1, To: 2}, {From: 3, To: 1}}
+	if !reflect.DeepEqual(qs, want) {
+		t.Errorf("ReadDIMACSQueries() got = %+v, want %+v", qs, want)
+	}
+}
@@ -0,0 +1,73 @@
+package graphio
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT writes the graph in GraphViz DOT format. Vertex positions are
+// written as pinned pos attributes with the Y axis pointing up as GraphViz
+// expects, edge weights are written as labels. Undirected edges of
+// directed graphs get dir=none attribute.
+func WriteDOT(w io.Writer, g Graph) error {
+	bw := bufio.NewWriter(w)
+
+	directed := g.Graph.Directed
+	for _, e := range g.Edges {
+		directed = directed || !e.Undirected
+	}
+
+	op := " -- "
+	if directed {
+		op = " -> "
+		bw.WriteString("digraph ")
+	} else {
+		bw.WriteString("graph ")
+	}
+	bw.WriteString(dotQuote(g.Graph.Name) + " {\n")
+
+	for _, v := range g.Vertexes {
+		label := v.Label
+		if label == "" {
+			label = strconv.FormatInt(v.ID, 10)
+		}
+		bw.WriteString("\t" + strconv.FormatInt(v.ID, 10) +
+			" [label=" + dotQuote(label) +
+			", pos=" + dotQuote(formatFloat(v.X)+","+formatFloat(-v.Y)+"!") +
+			"];\n")
+	}
+
+	for _, e := range g.Edges {
+		label := formatFloat(e.Weight)
+		if e.Label != "" {
+			label = e.Label + " (" + label + ")"
+		}
+		bw.WriteString("\t" + strconv.FormatInt(e.From, 10) + op +
+			strconv.FormatInt(e.To, 10) +
+			" [id=" + dotQuote("e"+strconv.FormatInt(e.ID, 10)) +
+			", label=" + dotQuote(label))
+		if directed && e.Undirected {
+			bw.WriteString(", dir=none")
+		}
+		bw.WriteString("];\n")
+	}
+
+	bw.WriteString("}\n")
+
+	return bw.Flush()
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		f = 0 // Negative zero is written as zero.
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+package graphio
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestWriteDOT(t *testing.T) {
+	g := Graph{
+		Graph: entity.Graph{Name: `roads "A"`, Directed: true},
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 0, Y: 0},
+			{ID: 2, X: 10.5, Y: 20, Label: "B"},
+		},
+		Edges: []entity.Edge{
+			{ID: 3, From: 1, To: 2, Weight: 2},
+			{ID: 4, From: 2, To: 1, Weight: -1.5, Undirected: true,
+				Label: "back"},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteDOT(&buf, g)
+	if err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+
+	want := `digraph "roads \"A\"" {
+	1 [label="1", pos="0,0!"];
+	2 [label="B", pos="10.5,-20!"];
+	1 -> 2 [id="e3", label="2"];
+	2 -> 1 [id="e4", label="back (-1.5)", dir=none];
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDOT() got = %s, want %s", buf.String(), want)
+	}
+
+	g.Graph.Directed = false
+	g.Edges = g.Edges[1:]
+	buf.Reset()
+
+	err = WriteDOT(&buf, g)
+	if err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+
+	want = `graph "roads \"A\"" {
+	1 [label="1", pos="0,0!"];
+	2 [label="B", pos="10.5,-20!"];
+	2 -- 1 [id="e4", label="back (-1.5)"];
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDOT() got = %s, want %s", buf.String(), want)
+	}
+}
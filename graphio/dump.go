@@ -0,0 +1,97 @@
+package graphio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// DumpVersion is the version of dump format written by WriteDump.
+const DumpVersion = 1
+
+// dump is the self-contained JSON document with the graph, its vertexes
+// and edges.
+type dump struct {
+	Version  int             `json:"version"`
+	DumpedAt time.Time       `json:"dumped_at"`
+	Graph    entity.Graph    `json:"graph"`
+	Vertexes []entity.Vertex `json:"vertexes"`
+	Edges    []entity.Edge   `json:"edges"`
+}
+
+// WriteDump writes the graph as the dump document followed by the newline,
+// so dumps of several graphs written one by one form NDJSON.
+func WriteDump(w io.Writer, g Graph) error {
+	d := dump{
+		Version:  DumpVersion,
+		DumpedAt: time.Now().UTC(),
+		Graph:    g.Graph,
+		Vertexes: g.Vertexes,
+		Edges:    g.Edges,
+	}
+
+	if d.Vertexes == nil {
+		d.Vertexes = []entity.Vertex{}
+	}
+	if d.Edges == nil {
+		d.Edges = []entity.Edge{}
+	}
+
+	return json.NewEncoder(w).Encode(d)
+}
+
+// DumpReader reads graphs from the sequence of dump documents, for example
+// from the single dump or from NDJSON with dumps of several graphs.
+type DumpReader struct {
+	d *json.Decoder
+}
+
+func NewDumpReader(r io.Reader) *DumpReader {
+	return &DumpReader{d: json.NewDecoder(r)}
+}
+
+// Next reads the next graph. Vertex and edge IDs of the graph are the
+// dumped ones, they only link edges with vertexes. io.EOF is returned
+// when there are no more dumps.
+func (r *DumpReader) Next() (Graph, error) {
+	var d dump
+
+	err := r.d.Decode(&d)
+	if err != nil {
+		if err == io.EOF {
+			return Graph{}, err
+		}
+		return Graph{}, fmt.Errorf("decode dump: %w", err)
+	}
+
+	if d.Version < 1 || d.Version > DumpVersion {
+		return Graph{}, fmt.Errorf("unsupported dump version %d",
+			d.Version)
+	}
+
+	ids := make(map[int64]bool, len(d.Vertexes))
+
+	for _, v := range d.Vertexes {
+		if ids[v.ID] {
+			return Graph{}, fmt.Errorf("graph %q: duplicated vertex %d",
+				d.Graph.Name, v.ID)
+		}
+		ids[v.ID] = true
+	}
+
+	for _, e := range d.Edges {
+		if !ids[e.From] || !ids[e.To] {
+			return Graph{}, fmt.Errorf("graph %q: edge %d references "+
+				"unknown vertex", d.Graph.Name, e.ID)
+		}
+	}
+
+	return Graph{
+		Graph:    d.Graph,
+		Vertexes: d.Vertexes,
+		Edges:    d.Edges,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package graphio
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestDump_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	var (
+		buf bytes.Buffer
+		gs  []Graph
+	)
+
+	for i := 0; i < 100; i++ {
+		g := randomGraph(r)
+		g.Graph.ID = int64(i)
+		if g.Edges == nil {
+			g.Edges = []entity.Edge{}
+		}
+
+		err := WriteDump(&buf, g)
+		if err != nil {
+			t.Fatalf("WriteDump() error = %v", err)
+		}
+
+		gs = append(gs, g)
+	}
+
+	dr := NewDumpReader(&buf)
+
+	for _, g := range gs {
+		got, err := dr.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, g) {
+			t.Fatalf("Next() got = %+v, want %+v", got, g)
+		}
+	}
+
+	_, err := dr.Next()
+	if err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestDumpReader_errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"version": `{"version":2,"graph":{"name":"g"}}`,
+		"vertex":  `{"version":1,"vertexes":[{"id":1},{"id":1}]}`,
+		"edge":    `{"version":1,"vertexes":[{"id":1}],"edges":[{"from":1,"to":2}]}`,
+		"invalid": `{"version":`,
+	} {
+		_, err := NewDumpReader(strings.NewReader(data)).Next()
+		if err == nil || err == io.EOF {
+			t.Errorf("%s: Next() error = %v, want error", name, err)
+		}
+	}
+}
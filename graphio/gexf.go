@@ -0,0 +1,388 @@
+package graphio
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+)
+
+const (
+	gexfNamespace    = "http://gexf.net/1.3"
+	gexfVizNamespace = "http://gexf.net/1.3/viz"
+)
+
+type gexfFile struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Version string    `xml:"version,attr,omitempty"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr,omitempty"`
+	Mode            string           `xml:"mode,attr,omitempty"`
+	Name            string           `xml:"name,attr,omitempty"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+// gexfAttValues is the attvalues element. It is a pointer in elements
+// to omit empty attvalues.
+type gexfAttValues struct {
+	AttValues []gexfAttValue `xml:"attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfPosition struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+// MarshalXML writes the position in viz namespace. Positions are read
+// from any namespace since it differs between GEXF versions.
+func (p gexfPosition) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name.Space = gexfVizNamespace
+	type position gexfPosition
+	return e.EncodeElement(position(p), start)
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues *gexfAttValues `xml:"attvalues"`
+	Position  *gexfPosition  `xml:"position"`
+	Nodes     *struct {
+		Nodes []gexfNode `xml:"node"`
+	} `xml:"nodes"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Type      string         `xml:"type,attr,omitempty"`
+	Weight    *float64       `xml:"weight,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues *gexfAttValues `xml:"attvalues"`
+}
+
+// ReadGEXF reads the graph from GEXF file. Node positions are taken from
+// viz:position elements and attribute values are converted according to
+// attribute types. Nested nodes are skipped.
+func ReadGEXF(r io.Reader) (Graph, Report, error) {
+	var f gexfFile
+
+	err := xml.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return Graph{}, Report{}, fmt.Errorf("decode GEXF: %w", err)
+	}
+
+	var report Report
+
+	g := Graph{
+		Graph: entity.Graph{
+			Name:     f.Graph.Name,
+			Directed: f.Graph.DefaultEdgeType != "undirected",
+		},
+	}
+
+	attributes := map[string]map[string]gexfAttribute{}
+	for _, as := range f.Graph.Attributes {
+		if attributes[as.Class] == nil {
+			attributes[as.Class] = map[string]gexfAttribute{}
+		}
+		for _, a := range as.Attributes {
+			attributes[as.Class][a.ID] = a
+		}
+	}
+
+	ids := make(map[string]int64, len(f.Graph.Nodes))
+
+	for _, n := range f.Graph.Nodes {
+		if _, exists := ids[n.ID]; exists {
+			report.skip("node", n.ID, "duplicated node ID")
+			continue
+		}
+
+		if n.Nodes != nil {
+			for _, nn := range n.Nodes.Nodes {
+				report.skip("node", nn.ID, "nested nodes are not supported")
+			}
+		}
+
+		v := entity.Vertex{
+			ID:    int64(len(g.Vertexes) + 1),
+			Label: n.Label,
+		}
+		if n.Position != nil {
+			if finite(n.Position.X) && finite(n.Position.Y) {
+				v.X, v.Y = n.Position.X, n.Position.Y
+			} else {
+				report.skip("node", n.ID, "invalid position")
+			}
+		}
+		v.Attributes = gexfAttributeValues(attributes["node"], n.AttValues,
+			"node", n.ID, &report)
+
+		ids[n.ID] = v.ID
+		g.Vertexes = append(g.Vertexes, v)
+	}
+
+	for _, ge := range f.Graph.Edges {
+		from, exists := ids[ge.Source]
+		if !exists {
+			report.skip("edge", ge.ID, "unknown source node "+ge.Source)
+			continue
+		}
+		to, exists := ids[ge.Target]
+		if !exists {
+			report.skip("edge", ge.ID, "unknown target node "+ge.Target)
+			continue
+		}
+
+		e := entity.Edge{
+			ID:         int64(len(g.Edges) + 1),
+			From:       from,
+			To:         to,
+			Weight:     1,
+			Undirected: !g.Graph.Directed,
+			Label:      ge.Label,
+		}
+
+		switch ge.Type {
+		case "directed":
+			e.Undirected = false
+		case "undirected":
+			e.Undirected = true
+		case "mutual":
+			report.skip("edge", ge.ID,
+				"mutual edge is imported as undirected")
+			e.Undirected = true
+		}
+
+		if ge.Weight != nil {
+			if !finite(*ge.Weight) {
+				report.skip("edge", ge.ID, "invalid weight "+
+					formatFloat(*ge.Weight))
+				continue
+			}
+			e.Weight = *ge.Weight
+		}
+		e.Attributes = gexfAttributeValues(attributes["edge"], ge.AttValues,
+			"edge", ge.ID, &report)
+
+		g.Edges = append(g.Edges, e)
+	}
+
+	return g, report, nil
+}
+
+// gexfAttributeValues converts attribute values of the element to attributes.
+func gexfAttributeValues(declared map[string]gexfAttribute,
+	vs *gexfAttValues, element string, id string,
+	report *Report) entity.Attributes {
+
+	if vs == nil || len(vs.AttValues) == 0 {
+		return nil
+	}
+
+	as := make(entity.Attributes, len(vs.AttValues))
+
+	for _, v := range vs.AttValues {
+		a, exists := declared[v.For]
+		if !exists {
+			report.skip("attvalue", id, "undeclared "+element+
+				" attribute "+v.For)
+			continue
+		}
+
+		var (
+			value interface{}
+			err   error
+		)
+
+		switch a.Type {
+		case "integer", "long", "short", "byte":
+			value, err = strconv.ParseInt(v.Value, 10, 64)
+		case "float", "double":
+			value, err = parseFloat(v.Value)
+		case "boolean":
+			value, err = strconv.ParseBool(v.Value)
+		case "json":
+			err = json.Unmarshal([]byte(v.Value), &value)
+		default:
+			value = v.Value
+		}
+		if err != nil {
+			report.skip("attvalue", id, "invalid "+a.Type+" value of "+
+				element+" attribute "+a.Title)
+			continue
+		}
+
+		as[a.Title] = value
+	}
+
+	return as
+}
+
+// WriteGEXF writes the graph in GEXF 1.3 format. Attributes are declared
+// with types of their values, values which are not strings, numbers or
+// booleans are written as JSON with non-standard json type.
+func WriteGEXF(w io.Writer, g Graph) error {
+	f := gexfFile{
+		Version: "1.3",
+		Graph: gexfGraph{
+			Mode:            "static",
+			Name:            g.Graph.Name,
+			DefaultEdgeType: "undirected",
+			Nodes:           make([]gexfNode, 0, len(g.Vertexes)),
+			Edges:           make([]gexfEdge, 0, len(g.Edges)),
+		},
+	}
+
+	if g.Graph.Directed {
+		f.Graph.DefaultEdgeType = "directed"
+	}
+
+	nodeAttrs := newGEXFAttributes("node")
+	edgeAttrs := newGEXFAttributes("edge")
+
+	for _, v := range g.Vertexes {
+		f.Graph.Nodes = append(f.Graph.Nodes, gexfNode{
+			ID:        strconv.FormatInt(v.ID, 10),
+			Label:     v.Label,
+			AttValues: nodeAttrs.values(v.Attributes),
+			Position:  &gexfPosition{X: v.X, Y: v.Y},
+		})
+	}
+
+	for _, e := range g.Edges {
+		e := e
+		ge := gexfEdge{
+			ID:        strconv.FormatInt(e.ID, 10),
+			Source:    strconv.FormatInt(e.From, 10),
+			Target:    strconv.FormatInt(e.To, 10),
+			Type:      "undirected",
+			Weight:    &e.Weight,
+			Label:     e.Label,
+			AttValues: edgeAttrs.values(e.Attributes),
+		}
+		if !e.Undirected {
+			ge.Type = "directed"
+		}
+		f.Graph.Edges = append(f.Graph.Edges, ge)
+	}
+
+	for _, as := range []*gexfAttributeSet{nodeAttrs, edgeAttrs} {
+		if len(as.Attributes) > 0 {
+			f.Graph.Attributes = append(f.Graph.Attributes, as.gexfAttributes)
+		}
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+
+	// The namespace is set here since XMLName tag matches any namespace
+	// on reading but overrides the namespace on writing.
+	err = e.EncodeElement(f, xml.StartElement{
+		Name: xml.Name{Space: gexfNamespace, Local: "gexf"},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// gexfAttributeSet declares attributes while values are written.
+type gexfAttributeSet struct {
+	gexfAttributes
+	ids map[string]string
+}
+
+func newGEXFAttributes(class string) *gexfAttributeSet {
+	return &gexfAttributeSet{
+		gexfAttributes: gexfAttributes{Class: class},
+		ids:            map[string]string{},
+	}
+}
+
+func (s *gexfAttributeSet) values(as entity.Attributes) *gexfAttValues {
+	if len(as) == 0 {
+		return nil
+	}
+
+	titles := make([]string, 0, len(as))
+	for title := range as {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	vs := make([]gexfAttValue, 0, len(as))
+
+	for _, title := range titles {
+		var typ, value string
+
+		switch v := as[title].(type) {
+		case string:
+			typ, value = "string", v
+		case bool:
+			typ, value = "boolean", strconv.FormatBool(v)
+		case int64:
+			typ, value = "long", strconv.FormatInt(v, 10)
+		case float64:
+			typ, value = "double", formatFloat(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			typ, value = "json", string(data)
+		}
+
+		// Attributes with the same title and different types are declared
+		// separately.
+		key := title + "\x00" + typ
+		id, exists := s.ids[key]
+		if !exists {
+			id = strconv.Itoa(len(s.Attributes))
+			s.ids[key] = id
+			s.Attributes = append(s.Attributes, gexfAttribute{
+				ID:    id,
+				Title: title,
+				Type:  typ,
+			})
+		}
+
+		vs = append(vs, gexfAttValue{For: id, Value: value})
+	}
+
+	return &gexfAttValues{AttValues: vs}
+}
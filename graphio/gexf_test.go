@@ -0,0 +1,92 @@
+package graphio
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestGEXF_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		g := randomGraph(r)
+
+		var buf bytes.Buffer
+		err := WriteGEXF(&buf, g)
+		if err != nil {
+			t.Fatalf("WriteGEXF() error = %v", err)
+		}
+
+		got, report, err := ReadGEXF(&buf)
+		if err != nil {
+			t.Fatalf("ReadGEXF() error = %v", err)
+		}
+		if len(report.Skipped) > 0 {
+			t.Errorf("ReadGEXF() skipped = %+v", report.Skipped)
+		}
+
+		if !reflect.DeepEqual(got, g) {
+			t.Fatalf("ReadGEXF() got = %+v, want %+v", got, g)
+		}
+	}
+}
+
+func TestReadGEXF(t *testing.T) {
+	const gexf = `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://www.gexf.net/1.2draft"
+	xmlns:viz="http://www.gexf.net/1.2draft/viz" version="1.2">
+	<graph defaultedgetype="undirected">
+		<attributes class="node">
+			<attribute id="0" title="count" type="integer"/>
+			<attribute id="1" title="size" type="double"/>
+		</attributes>
+		<nodes>
+			<node id="a" label="A">
+				<attvalues><attvalue for="0" value="5"/></attvalues>
+				<viz:position x="1.5" y="-2" z="0"/>
+			</node>
+			<node id="b">
+				<attvalues><attvalue for="1" value="NaN"/></attvalues>
+			</node>
+			<node id="c">
+				<viz:position x="Inf" y="0"/>
+			</node>
+		</nodes>
+		<edges>
+			<edge id="0" source="a" target="b" type="directed"/>
+			<edge id="1" source="a" target="x"/>
+			<edge id="2" source="b" target="c" weight="-Inf"/>
+		</edges>
+	</graph>
+</gexf>`
+
+	g, report, err := ReadGEXF(strings.NewReader(gexf))
+	if err != nil {
+		t.Fatalf("ReadGEXF() error = %v", err)
+	}
+
+	want := Graph{
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 1.5, Y: -2, Label: "A",
+				Attributes: entity.Attributes{"count": int64(5)}},
+			{ID: 2, Attributes: entity.Attributes{}},
+			{ID: 3},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 1},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadGEXF() got = %+v, want %+v", g, want)
+	}
+
+	if len(report.Skipped) != 4 {
+		t.Errorf("ReadGEXF() skipped = %+v, want 4 elements",
+			report.Skipped)
+	}
+}
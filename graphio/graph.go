@@ -0,0 +1,55 @@
+// Package graphio reads and writes graphs in interchange formats.
+package graphio
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// Graph is the graph read from or written to a file. Vertex and edge IDs of
+// the read graph are local to it and only link edges with vertexes.
+type Graph struct {
+	Graph    entity.Graph
+	Vertexes []entity.Vertex
+	Edges    []entity.Edge
+}
+
+// Skipped is the file element which was not imported.
+type Skipped struct {
+	Element string `json:"element"`
+	ID      string `json:"id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Report lists elements which were skipped while reading the file.
+type Report struct {
+	Skipped []Skipped `json:"skipped"`
+}
+
+// parseFloat parses the finite number. NaN and infinities are not valid
+// weights, coordinates or attribute values.
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if !finite(f) {
+		return 0, errors.New("not finite number " + s)
+	}
+	return f, nil
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func (r *Report) skip(element string, id string, reason string) {
+	r.Skipped = append(r.Skipped, Skipped{
+		Element: element,
+		ID:      id,
+		Reason:  reason,
+	})
+}
@@ -0,0 +1,394 @@
+package graphio
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/graph/entity"
+)
+
+var ErrNoGraph = errors.New("file has no graph")
+
+// DefaultWeightKey is the name of GraphML key with edge weights used if
+// no other is given.
+const DefaultWeightKey = "weight"
+
+type graphmlFile struct {
+	Keys   []graphmlKey   `xml:"key"`
+	Graphs []graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID      string  `xml:"id,attr"`
+	For     string  `xml:"for,attr"`
+	Name    string  `xml:"attr.name,attr"`
+	Type    string  `xml:"attr.type,attr"`
+	Default *string `xml:"default"`
+}
+
+type graphmlGraph struct {
+	ID          string           `xml:"id,attr"`
+	EdgeDefault string           `xml:"edgedefault,attr"`
+	Data        []graphmlData    `xml:"data"`
+	Nodes       []graphmlNode    `xml:"node"`
+	Edges       []graphmlEdge    `xml:"edge"`
+	Hyperedges  []graphmlElement `xml:"hyperedge"`
+}
+
+type graphmlNode struct {
+	ID     string           `xml:"id,attr"`
+	Data   []graphmlData    `xml:"data"`
+	Ports  []graphmlElement `xml:"port"`
+	Graphs []graphmlElement `xml:"graph"`
+}
+
+type graphmlEdge struct {
+	ID       string           `xml:"id,attr"`
+	Source   string           `xml:"source,attr"`
+	Target   string           `xml:"target,attr"`
+	Directed string           `xml:"directed,attr"`
+	Data     []graphmlData    `xml:"data"`
+	Graphs   []graphmlElement `xml:"graph"`
+}
+
+type graphmlData struct {
+	Key      string           `xml:"key,attr"`
+	Text     string           `xml:",chardata"`
+	Children []graphmlElement `xml:",any"`
+}
+
+// graphmlElement is the arbitrary XML element, it is used for yEd graphics
+// and unsupported elements.
+type graphmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr       `xml:",any,attr"`
+	Text     string           `xml:",chardata"`
+	Children []graphmlElement `xml:",any"`
+}
+
+// find returns the first element with the given local name in the element
+// subtree.
+func (e *graphmlElement) find(name string) *graphmlElement {
+	if e.XMLName.Local == name {
+		return e
+	}
+	for i := range e.Children {
+		if f := e.Children[i].find(name); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func (e *graphmlElement) attr(name string) (string, bool) {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// graphmlReader converts GraphML elements to vertexes and edges.
+type graphmlReader struct {
+	keys      map[string]graphmlKey
+	weightKey string
+	report    Report
+	reported  map[string]bool
+}
+
+// ReadGraphML reads the first graph of GraphML file. Node positions are
+// taken from "x" and "y" keys or yEd node geometry, labels from "label"
+// keys or yEd labels and edge weights from the key with weightKey name or
+// ID. Values of other keys are stored as attributes. Elements which can not
+// be represented, like hyperedges, ports and nested graphs, are skipped and
+// listed in the report.
+func ReadGraphML(r io.Reader, weightKey string) (Graph, Report, error) {
+	var f graphmlFile
+
+	err := xml.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return Graph{}, Report{}, fmt.Errorf("decode GraphML: %w", err)
+	}
+
+	if len(f.Graphs) == 0 {
+		return Graph{}, Report{}, ErrNoGraph
+	}
+
+	if weightKey == "" {
+		weightKey = DefaultWeightKey
+	}
+
+	gr := &graphmlReader{
+		keys:      make(map[string]graphmlKey, len(f.Keys)),
+		weightKey: weightKey,
+		reported:  map[string]bool{},
+	}
+
+	for _, k := range f.Keys {
+		gr.keys[k.ID] = k
+	}
+
+	for _, g := range f.Graphs[1:] {
+		gr.report.skip("graph", g.ID, "only the first graph is imported")
+	}
+
+	g := gr.graph(f.Graphs[0])
+
+	return g, gr.report, nil
+}
+
+func (gr *graphmlReader) graph(gg graphmlGraph) Graph {
+	g := Graph{
+		Graph: entity.Graph{
+			Name:     gg.ID,
+			Directed: gg.EdgeDefault != "undirected",
+		},
+	}
+
+	for _, d := range gg.Data {
+		gr.report.skip("data", d.Key, "graph data is not supported")
+	}
+
+	for _, h := range gg.Hyperedges {
+		id, _ := h.attr("id")
+		gr.report.skip("hyperedge", id, "hyperedges are not supported")
+	}
+
+	ids := make(map[string]int64, len(gg.Nodes))
+
+	for _, n := range gg.Nodes {
+		if _, exists := ids[n.ID]; exists {
+			gr.report.skip("node", n.ID, "duplicated node ID")
+			continue
+		}
+
+		if len(n.Graphs) > 0 {
+			gr.report.skip("graph", n.ID, "nested graphs are not supported")
+		}
+		if len(n.Ports) > 0 {
+			gr.report.skip("port", n.ID, "ports are not supported")
+		}
+
+		v := entity.Vertex{
+			ID:         int64(len(g.Vertexes) + 1),
+			Attributes: entity.Attributes{},
+		}
+
+		gr.vertexData(&v, n)
+
+		ids[n.ID] = v.ID
+		g.Vertexes = append(g.Vertexes, v)
+	}
+
+	for _, ge := range gg.Edges {
+		from, exists := ids[ge.Source]
+		if !exists {
+			gr.report.skip("edge", ge.ID, "unknown source node "+ge.Source)
+			continue
+		}
+		to, exists := ids[ge.Target]
+		if !exists {
+			gr.report.skip("edge", ge.ID, "unknown target node "+ge.Target)
+			continue
+		}
+
+		if len(ge.Graphs) > 0 {
+			gr.report.skip("graph", ge.ID, "nested graphs are not supported")
+		}
+
+		e := entity.Edge{
+			ID:         int64(len(g.Edges) + 1),
+			From:       from,
+			To:         to,
+			Weight:     1,
+			Undirected: !g.Graph.Directed,
+			Attributes: entity.Attributes{},
+		}
+
+		switch ge.Directed {
+		case "true":
+			e.Undirected = false
+		case "false":
+			e.Undirected = true
+		}
+
+		err := gr.edgeData(&e, ge)
+		if err != nil {
+			gr.report.skip("edge", ge.ID, err.Error())
+			continue
+		}
+
+		g.Edges = append(g.Edges, e)
+	}
+
+	return g
+}
+
+// data returns values of the element data including defaults of keys
+// declared for the element kind.
+func (gr *graphmlReader) data(kind string, ds []graphmlData) []graphmlData {
+	set := make(map[string]bool, len(ds))
+	for _, d := range ds {
+		set[d.Key] = true
+	}
+
+	for id, k := range gr.keys {
+		if set[id] || k.Default == nil ||
+			(k.For != kind && k.For != "all") {
+			continue
+		}
+		ds = append(ds, graphmlData{Key: id, Text: *k.Default})
+	}
+
+	return ds
+}
+
+// name returns the name of the data key.
+func (gr *graphmlReader) name(d graphmlData) string {
+	if k, exists := gr.keys[d.Key]; exists && k.Name != "" {
+		return k.Name
+	}
+	return d.Key
+}
+
+func (gr *graphmlReader) vertexData(v *entity.Vertex, n graphmlNode) {
+	for _, d := range gr.data("node", n.Data) {
+		if len(d.Children) > 0 {
+			root := graphmlElement{Children: d.Children}
+
+			geometry := root.find("Geometry")
+			if geometry == nil {
+				gr.reportData(d)
+				continue
+			}
+
+			x, y, err := geometryCenter(geometry)
+			if err != nil {
+				gr.report.skip("data", d.Key, err.Error())
+				continue
+			}
+			v.X, v.Y = x, y
+
+			if label := root.find("NodeLabel"); label != nil {
+				v.Label = strings.TrimSpace(label.Text)
+			}
+			continue
+		}
+
+		name := gr.name(d)
+
+		switch strings.ToLower(name) {
+		case "x", "y":
+			f, err := parseFloat(strings.TrimSpace(d.Text))
+			if err != nil {
+				gr.report.skip("data", d.Key, "invalid "+name+
+					" of node "+n.ID)
+				continue
+			}
+			if strings.ToLower(name) == "x" {
+				v.X = f
+			} else {
+				v.Y = f
+			}
+		case "label":
+			v.Label = d.Text
+		default:
+			gr.attribute(v.Attributes, d)
+		}
+	}
+}
+
+func (gr *graphmlReader) edgeData(e *entity.Edge, ge graphmlEdge) error {
+	for _, d := range gr.data("edge", ge.Data) {
+		if len(d.Children) > 0 {
+			root := graphmlElement{Children: d.Children}
+			if label := root.find("EdgeLabel"); label != nil {
+				e.Label = strings.TrimSpace(label.Text)
+			} else {
+				gr.reportData(d)
+			}
+			continue
+		}
+
+		name := gr.name(d)
+
+		switch {
+		case name == gr.weightKey || d.Key == gr.weightKey:
+			w, err := parseFloat(strings.TrimSpace(d.Text))
+			if err != nil {
+				return errors.New("invalid weight " + d.Text)
+			}
+			e.Weight = w
+		case strings.ToLower(name) == "label":
+			e.Label = d.Text
+		default:
+			gr.attribute(e.Attributes, d)
+		}
+	}
+
+	return nil
+}
+
+// attribute adds the data value to attributes converting it to the key
+// type.
+func (gr *graphmlReader) attribute(as entity.Attributes, d graphmlData) {
+	var (
+		value interface{}
+		err   error
+		text  = strings.TrimSpace(d.Text)
+	)
+
+	switch gr.keys[d.Key].Type {
+	case "int", "long":
+		value, err = strconv.ParseInt(text, 10, 64)
+	case "float", "double":
+		value, err = parseFloat(text)
+	case "boolean":
+		value, err = strconv.ParseBool(text)
+	default:
+		value = d.Text
+	}
+
+	if err != nil {
+		gr.report.skip("data", d.Key, "invalid "+gr.keys[d.Key].Type+
+			" value "+d.Text)
+		return
+	}
+
+	as[gr.name(d)] = value
+}
+
+// reportData reports unsupported structured data once per key.
+func (gr *graphmlReader) reportData(d graphmlData) {
+	if gr.reported[d.Key] {
+		return
+	}
+	gr.reported[d.Key] = true
+	gr.report.skip("data", d.Key, "structured data is not supported")
+}
+
+// geometryCenter returns the center of yEd node geometry.
+func geometryCenter(g *graphmlElement) (float64, float64, error) {
+	var vs [4]float64
+	for i, name := range []string{"x", "y", "width", "height"} {
+		s, exists := g.attr(name)
+		if !exists {
+			continue
+		}
+		f, err := parseFloat(s)
+		if err != nil {
+			return 0, 0, errors.New("invalid geometry " + name)
+		}
+		vs[i] = f
+	}
+	x, y := vs[0]+vs[2]/2, vs[1]+vs[3]/2
+	if !finite(x) || !finite(y) {
+		return 0, 0, errors.New("invalid geometry")
+	}
+	return x, y, nil
+}
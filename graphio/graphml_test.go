@@ -0,0 +1,148 @@
+package graphio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+const testGraphML = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns"
+	xmlns:y="http://www.yworks.com/xml/graphml">
+	<key id="d0" for="node" attr.name="color" attr.type="string">
+		<default>red</default>
+	</key>
+	<key id="d1" for="edge" attr.name="cost" attr.type="double"/>
+	<key id="d2" for="node" yfiles.type="nodegraphics"/>
+	<key id="d3" for="node" attr.name="x" attr.type="double"/>
+	<key id="d4" for="node" attr.name="y" attr.type="double"/>
+	<key id="d5" for="edge" attr.name="lanes" attr.type="int"/>
+	<graph id="G" edgedefault="undirected">
+		<node id="a">
+			<data key="d3">1.5</data>
+			<data key="d4">-2</data>
+		</node>
+		<node id="b">
+			<data key="d0">blue</data>
+			<data key="d2">
+				<y:ShapeNode>
+					<y:Geometry x="10" y="20" width="30" height="40"/>
+					<y:NodeLabel>B</y:NodeLabel>
+				</y:ShapeNode>
+			</data>
+			<port name="p"/>
+		</node>
+		<edge id="e1" source="a" target="b" directed="true">
+			<data key="d1">2.5</data>
+			<data key="d5">3</data>
+		</edge>
+		<edge id="e2" source="b" target="a"/>
+		<edge id="e3" source="a" target="c"/>
+		<edge id="e4" source="a" target="b">
+			<data key="d1">x</data>
+		</edge>
+		<hyperedge id="h1"><endpoint node="a"/></hyperedge>
+	</graph>
+	<graph id="H"/>
+</graphml>`
+
+func TestReadGraphML(t *testing.T) {
+	g, report, err := ReadGraphML(strings.NewReader(testGraphML), "cost")
+	if err != nil {
+		t.Fatalf("ReadGraphML() error = %v", err)
+	}
+
+	want := Graph{
+		Graph: entity.Graph{Name: "G"},
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 1.5, Y: -2,
+				Attributes: entity.Attributes{"color": "red"}},
+			{ID: 2, X: 25, Y: 40, Label: "B",
+				Attributes: entity.Attributes{"color": "blue"}},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 2.5,
+				Attributes: entity.Attributes{"lanes": int64(3)}},
+			{ID: 2, From: 2, To: 1, Weight: 1, Undirected: true,
+				Attributes: entity.Attributes{}},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadGraphML() got = %+v, want %+v", g, want)
+	}
+
+	var elements []string
+	for _, s := range report.Skipped {
+		elements = append(elements, s.Element+":"+s.ID)
+	}
+	wantElements := []string{"graph:H", "hyperedge:h1", "port:b",
+		"edge:e3", "edge:e4"}
+	if !reflect.DeepEqual(elements, wantElements) {
+		t.Errorf("ReadGraphML() skipped = %v, want %v", elements,
+			wantElements)
+	}
+}
+
+func TestReadGraphML_noGraph(t *testing.T) {
+	_, _, err := ReadGraphML(strings.NewReader("<graphml/>"), "")
+	if err != ErrNoGraph {
+		t.Errorf("ReadGraphML() error = %v, want %v", err, ErrNoGraph)
+	}
+}
+
+func TestReadGraphML_nonFinite(t *testing.T) {
+	const graphml = `<graphml xmlns:y="http://www.yworks.com/xml/graphml">
+	<key id="d0" for="node" attr.name="x" attr.type="double"/>
+	<key id="d1" for="node" yfiles.type="nodegraphics"/>
+	<key id="d2" for="edge" attr.name="weight" attr.type="double"/>
+	<key id="d3" for="edge" attr.name="load" attr.type="double"/>
+	<graph id="G" edgedefault="directed">
+		<node id="a">
+			<data key="d0">NaN</data>
+		</node>
+		<node id="b">
+			<data key="d1">
+				<y:ShapeNode><y:Geometry x="Inf" y="0"/></y:ShapeNode>
+			</data>
+		</node>
+		<edge id="e1" source="a" target="b">
+			<data key="d2">-Inf</data>
+		</edge>
+		<edge id="e2" source="b" target="a">
+			<data key="d3">nan</data>
+		</edge>
+	</graph>
+</graphml>`
+
+	g, report, err := ReadGraphML(strings.NewReader(graphml), "weight")
+	if err != nil {
+		t.Fatalf("ReadGraphML() error = %v", err)
+	}
+
+	want := Graph{
+		Graph: entity.Graph{Name: "G", Directed: true},
+		Vertexes: []entity.Vertex{
+			{ID: 1, Attributes: entity.Attributes{}},
+			{ID: 2, Attributes: entity.Attributes{}},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 2, To: 1, Weight: 1,
+				Attributes: entity.Attributes{}},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadGraphML() got = %+v, want %+v", g, want)
+	}
+
+	var elements []string
+	for _, s := range report.Skipped {
+		elements = append(elements, s.Element+":"+s.ID)
+	}
+	wantElements := []string{"data:d0", "data:d1", "edge:e1", "data:d3"}
+	if !reflect.DeepEqual(elements, wantElements) {
+		t.Errorf("ReadGraphML() skipped = %v, want %v", elements,
+			wantElements)
+	}
+}
@@ -0,0 +1,280 @@
+package graphio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+)
+
+type jgfFile struct {
+	Graph  *jgfGraph  `json:"graph,omitempty"`
+	Graphs []jgfGraph `json:"graphs,omitempty"`
+}
+
+type jgfGraph struct {
+	ID       string          `json:"id,omitempty"`
+	Label    string          `json:"label,omitempty"`
+	Directed *bool           `json:"directed,omitempty"`
+	Nodes    jgfNodes        `json:"nodes"`
+	Edges    []jgfEdge       `json:"edges"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+type jgfNode struct {
+	ID       string          `json:"id,omitempty"`
+	Label    string          `json:"label,omitempty"`
+	Metadata jgfNodeMetadata `json:"metadata"`
+}
+
+// Numbers of metadata are decoded as is so that numbers out of float64
+// range skip the element rather than fail the whole file.
+type jgfNodeMetadata struct {
+	X          json.Number       `json:"x"`
+	Y          json.Number       `json:"y"`
+	Attributes entity.Attributes `json:"attributes,omitempty"`
+}
+
+type jgfEdge struct {
+	ID       string          `json:"id,omitempty"`
+	Source   string          `json:"source"`
+	Target   string          `json:"target"`
+	Directed *bool           `json:"directed,omitempty"`
+	Label    string          `json:"label,omitempty"`
+	Metadata jgfEdgeMetadata `json:"metadata"`
+}
+
+type jgfEdgeMetadata struct {
+	Weight     *json.Number      `json:"weight,omitempty"`
+	Attributes entity.Attributes `json:"attributes,omitempty"`
+}
+
+// jgfNodes are nodes in the order of the file. JGF 2 stores them in the
+// object by IDs and JGF 1 in the array.
+type jgfNodes []jgfNode
+
+func (ns jgfNodes) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, n := range ns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		id, err := json.Marshal(n.ID)
+		if err != nil {
+			return nil, err
+		}
+		n.ID = ""
+		node, err := json.Marshal(n)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(id)
+		buf.WriteByte(':')
+		buf.Write(node)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func (ns *jgfNodes) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]jgfNode)(ns))
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if t != json.Delim('{') {
+		return fmt.Errorf("nodes must be object or array")
+	}
+
+	for d.More() {
+		t, err = d.Token()
+		if err != nil {
+			return err
+		}
+
+		var n jgfNode
+		err = d.Decode(&n)
+		if err != nil {
+			return err
+		}
+		n.ID = t.(string)
+
+		*ns = append(*ns, n)
+	}
+
+	_, err = d.Token()
+	return err
+}
+
+// ReadJGF reads the first graph of JSON Graph Format file, both version 1
+// with nodes array and version 2 with nodes object are supported. Node
+// positions are taken from x and y metadata, edge weights from weight
+// metadata and attributes from attributes metadata.
+func ReadJGF(r io.Reader) (Graph, Report, error) {
+	var f jgfFile
+
+	err := json.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return Graph{}, Report{}, fmt.Errorf("decode JGF: %w", err)
+	}
+
+	var report Report
+
+	graphs := f.Graphs
+	if f.Graph != nil {
+		graphs = append([]jgfGraph{*f.Graph}, graphs...)
+	}
+
+	if len(graphs) == 0 {
+		return Graph{}, Report{}, ErrNoGraph
+	}
+
+	for _, g := range graphs[1:] {
+		report.skip("graph", g.ID, "only the first graph is imported")
+	}
+
+	jg := graphs[0]
+
+	g := Graph{
+		Graph: entity.Graph{
+			Name:     jg.Label,
+			Directed: jg.Directed == nil || *jg.Directed,
+		},
+	}
+
+	if g.Graph.Name == "" {
+		g.Graph.Name = jg.ID
+	}
+
+	if len(jg.Metadata) > 0 {
+		report.skip("metadata", jg.ID, "graph metadata is not supported")
+	}
+
+	ids := make(map[string]int64, len(jg.Nodes))
+
+	for _, n := range jg.Nodes {
+		if _, exists := ids[n.ID]; exists {
+			report.skip("node", n.ID, "duplicated node ID")
+			continue
+		}
+
+		v := entity.Vertex{
+			ID:         int64(len(g.Vertexes) + 1),
+			Label:      n.Label,
+			Attributes: n.Metadata.Attributes,
+		}
+
+		x, errX := jgfFloat(n.Metadata.X)
+		y, errY := jgfFloat(n.Metadata.Y)
+		if errX != nil || errY != nil {
+			report.skip("node", n.ID, "invalid position")
+		} else {
+			v.X, v.Y = x, y
+		}
+
+		ids[n.ID] = v.ID
+		g.Vertexes = append(g.Vertexes, v)
+	}
+
+	for _, je := range jg.Edges {
+		from, exists := ids[je.Source]
+		if !exists {
+			report.skip("edge", je.ID, "unknown source node "+je.Source)
+			continue
+		}
+		to, exists := ids[je.Target]
+		if !exists {
+			report.skip("edge", je.ID, "unknown target node "+je.Target)
+			continue
+		}
+
+		e := entity.Edge{
+			ID:         int64(len(g.Edges) + 1),
+			From:       from,
+			To:         to,
+			Weight:     1,
+			Undirected: !g.Graph.Directed,
+			Label:      je.Label,
+			Attributes: je.Metadata.Attributes,
+		}
+		if je.Directed != nil {
+			e.Undirected = !*je.Directed
+		}
+		if je.Metadata.Weight != nil {
+			e.Weight, err = jgfFloat(*je.Metadata.Weight)
+			if err != nil {
+				report.skip("edge", je.ID, "invalid weight "+
+					je.Metadata.Weight.String())
+				continue
+			}
+		}
+
+		g.Edges = append(g.Edges, e)
+	}
+
+	return g, report, nil
+}
+
+// jgfFloat returns the finite number of metadata, missing number is zero.
+func jgfFloat(n json.Number) (float64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	return parseFloat(n.String())
+}
+
+// WriteJGF writes the graph in JSON Graph Format version 2.
+func WriteJGF(w io.Writer, g Graph) error {
+	jg := jgfGraph{
+		ID:       strconv.FormatInt(g.Graph.ID, 10),
+		Label:    g.Graph.Name,
+		Directed: &g.Graph.Directed,
+		Nodes:    make(jgfNodes, 0, len(g.Vertexes)),
+		Edges:    make([]jgfEdge, 0, len(g.Edges)),
+	}
+
+	for _, v := range g.Vertexes {
+		jg.Nodes = append(jg.Nodes, jgfNode{
+			ID:    strconv.FormatInt(v.ID, 10),
+			Label: v.Label,
+			Metadata: jgfNodeMetadata{
+				X:          json.Number(formatFloat(v.X)),
+				Y:          json.Number(formatFloat(v.Y)),
+				Attributes: v.Attributes,
+			},
+		})
+	}
+
+	for _, e := range g.Edges {
+		directed := !e.Undirected
+		weight := json.Number(formatFloat(e.Weight))
+		jg.Edges = append(jg.Edges, jgfEdge{
+			ID:       strconv.FormatInt(e.ID, 10),
+			Source:   strconv.FormatInt(e.From, 10),
+			Target:   strconv.FormatInt(e.To, 10),
+			Directed: &directed,
+			Label:    e.Label,
+			Metadata: jgfEdgeMetadata{
+				Weight:     &weight,
+				Attributes: e.Attributes,
+			},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(jgfFile{Graph: &jg})
+}
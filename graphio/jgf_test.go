@@ -0,0 +1,123 @@
+package graphio
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+// randomGraph returns the random graph with local IDs as they are numbered
+// by readers.
+func randomGraph(r *rand.Rand) Graph {
+	g := Graph{
+		Graph: entity.Graph{Name: "random", Directed: r.Intn(2) == 0},
+	}
+
+	n := r.Intn(10) + 1
+	for i := 1; i <= n; i++ {
+		v := entity.Vertex{
+			ID: int64(i),
+			X:  r.NormFloat64() * 100,
+			Y:  r.NormFloat64() * 100,
+		}
+		if r.Intn(2) == 0 {
+			v.Label = "v" + strings.Repeat("<&\"", r.Intn(2))
+			v.Attributes = entity.Attributes{
+				"color": "red",
+				"size":  r.Float64(),
+				"ok":    true,
+			}
+		}
+		g.Vertexes = append(g.Vertexes, v)
+	}
+
+	m := r.Intn(20)
+	for i := 1; i <= m; i++ {
+		e := entity.Edge{
+			ID:         int64(i),
+			From:       int64(r.Intn(n) + 1),
+			To:         int64(r.Intn(n) + 1),
+			Weight:     r.NormFloat64() * 10,
+			Undirected: r.Intn(2) == 0,
+		}
+		if r.Intn(2) == 0 {
+			e.Label = "e"
+			e.Attributes = entity.Attributes{"lanes": 2.0}
+		}
+		g.Edges = append(g.Edges, e)
+	}
+
+	return g
+}
+
+func TestJGF_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		g := randomGraph(r)
+
+		var buf bytes.Buffer
+		err := WriteJGF(&buf, g)
+		if err != nil {
+			t.Fatalf("WriteJGF() error = %v", err)
+		}
+
+		got, report, err := ReadJGF(&buf)
+		if err != nil {
+			t.Fatalf("ReadJGF() error = %v", err)
+		}
+		if len(report.Skipped) > 0 {
+			t.Errorf("ReadJGF() skipped = %+v", report.Skipped)
+		}
+
+		if !reflect.DeepEqual(got, g) {
+			t.Fatalf("ReadJGF() got = %+v, want %+v", got, g)
+		}
+	}
+}
+
+func TestReadJGF_version1(t *testing.T) {
+	const jgf = `{"graphs": [{
+		"label": "g",
+		"directed": false,
+		"nodes": [
+			{"id": "a", "metadata": {"x": 1, "y": 2}},
+			{"id": "b"},
+			{"id": "c", "metadata": {"x": 1e999, "y": 0}}
+		],
+		"edges": [
+			{"source": "a", "target": "b", "metadata": {"weight": 3}},
+			{"source": "a", "target": "x"},
+			{"source": "b", "target": "a", "metadata": {"weight": -1e400}}
+		]
+	}, {"label": "h"}]}`
+
+	g, report, err := ReadJGF(strings.NewReader(jgf))
+	if err != nil {
+		t.Fatalf("ReadJGF() error = %v", err)
+	}
+
+	want := Graph{
+		Graph: entity.Graph{Name: "g"},
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 1, Y: 2},
+			{ID: 2},
+			{ID: 3},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 3, Undirected: true},
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadJGF() got = %+v, want %+v", g, want)
+	}
+
+	if len(report.Skipped) != 4 {
+		t.Errorf("ReadJGF() skipped = %+v, want 4 elements",
+			report.Skipped)
+	}
+}
@@ -0,0 +1,148 @@
+package graphio
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	svgPadding      = 40
+	svgVertexRadius = 12
+	svgLoopRadius   = 14
+
+	svgEdgeColor      = "#848484"
+	svgHighlightColor = "#e00000"
+)
+
+// WriteSVG draws the graph at stored vertex positions as SVG image.
+// Edges with IDs from highlight are drawn in red, it is used to show paths.
+func WriteSVG(w io.Writer, g Graph, highlight []int64) error {
+	bw := bufio.NewWriter(w)
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, v := range g.Vertexes {
+		minX, maxX = math.Min(minX, v.X), math.Max(maxX, v.X)
+		minY, maxY = math.Min(minY, v.Y), math.Max(maxY, v.Y)
+	}
+	if len(g.Vertexes) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+
+	pad := float64(svgPadding)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" `+
+		`viewBox="%s %s %s %s" width="%s" height="%s">`+"\n",
+		formatFloat(minX-pad), formatFloat(minY-pad),
+		formatFloat(maxX-minX+2*pad), formatFloat(maxY-minY+2*pad),
+		formatFloat(maxX-minX+2*pad), formatFloat(maxY-minY+2*pad))
+
+	bw.WriteString("<defs>\n")
+	for _, m := range []struct{ id, color string }{
+		{"arrow", svgEdgeColor},
+		{"arrow-highlighted", svgHighlightColor},
+	} {
+		fmt.Fprintf(bw, `<marker id="%s" viewBox="0 0 10 10" refX="10" `+
+			`refY="5" markerWidth="6" markerHeight="6" orient="auto">`+
+			`<path d="M 0 0 L 10 5 L 0 10 z" fill="%s"/></marker>`+"\n",
+			m.id, m.color)
+	}
+	bw.WriteString("</defs>\n")
+
+	vertexes := make(map[int64]int, len(g.Vertexes))
+	for i, v := range g.Vertexes {
+		vertexes[v.ID] = i
+	}
+
+	highlighted := make(map[int64]bool, len(highlight))
+	for _, id := range highlight {
+		highlighted[id] = true
+	}
+
+	// Highlighted edges are drawn last to be on top of others.
+	for _, top := range []bool{false, true} {
+		for _, e := range g.Edges {
+			if highlighted[e.ID] != top {
+				continue
+			}
+			fi, exists := vertexes[e.From]
+			if !exists {
+				continue
+			}
+			ti, exists := vertexes[e.To]
+			if !exists {
+				continue
+			}
+			writeSVGEdge(bw, e.Label, e.Weight, !e.Undirected, top,
+				g.Vertexes[fi].X, g.Vertexes[fi].Y,
+				g.Vertexes[ti].X, g.Vertexes[ti].Y)
+		}
+	}
+
+	for _, v := range g.Vertexes {
+		label := v.Label
+		if label == "" {
+			label = strconv.FormatInt(v.ID, 10)
+		}
+		fmt.Fprintf(bw, `<circle cx="%s" cy="%s" r="%d" fill="#97c2fc" `+
+			`stroke="#2b7ce9"/>`+"\n", formatFloat(v.X), formatFloat(v.Y),
+			svgVertexRadius)
+		writeSVGText(bw, v.X, v.Y+svgVertexRadius+14, label)
+	}
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
+
+func writeSVGEdge(w *bufio.Writer, label string, weight float64,
+	directed bool, highlighted bool, x1, y1, x2, y2 float64) {
+
+	color, width, arrow := svgEdgeColor, 1, "arrow"
+	if highlighted {
+		color, width, arrow = svgHighlightColor, 3, "arrow-highlighted"
+	}
+
+	marker := ""
+	if directed {
+		marker = ` marker-end="url(#` + arrow + `)"`
+	}
+
+	text := formatFloat(weight)
+	if label != "" {
+		text = label + " (" + text + ")"
+	}
+
+	dx, dy := x2-x1, y2-y1
+	d := math.Hypot(dx, dy)
+
+	if d <= 2*svgVertexRadius {
+		// Loops and edges between overlapping vertexes are drawn as
+		// circles above the vertex.
+		fmt.Fprintf(w, `<circle cx="%s" cy="%s" r="%d" fill="none" `+
+			`stroke="%s" stroke-width="%d"/>`+"\n",
+			formatFloat(x1), formatFloat(y1-svgLoopRadius-svgVertexRadius/2),
+			svgLoopRadius, color, width)
+		writeSVGText(w, x1, y1-2*svgLoopRadius-svgVertexRadius, text)
+		return
+	}
+
+	// Lines start and end at vertex circles borders.
+	ux, uy := dx/d*svgVertexRadius, dy/d*svgVertexRadius
+	fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" `+
+		`stroke-width="%d"%s/>`+"\n",
+		formatFloat(x1+ux), formatFloat(y1+uy),
+		formatFloat(x2-ux), formatFloat(y2-uy), color, width, marker)
+	writeSVGText(w, (x1+x2)/2, (y1+y2)/2-4, text)
+}
+
+func writeSVGText(w *bufio.Writer, x, y float64, text string) {
+	fmt.Fprintf(w, `<text x="%s" y="%s" text-anchor="middle" `+
+		`font-family="sans-serif" font-size="12">`,
+		formatFloat(x), formatFloat(y))
+	xml.EscapeText(w, []byte(text))
+	w.WriteString("</text>\n")
+}
@@ -0,0 +1,66 @@
+package graphio
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func TestWriteSVG(t *testing.T) {
+	g := Graph{
+		Vertexes: []entity.Vertex{
+			{ID: 1, X: 0, Y: 0, Label: "<a>"},
+			{ID: 2, X: 100, Y: 0},
+			{ID: 3, X: 100, Y: 100},
+		},
+		Edges: []entity.Edge{
+			{ID: 1, From: 1, To: 2, Weight: 1},
+			{ID: 2, From: 2, To: 3, Weight: 1, Undirected: true},
+			{ID: 3, From: 3, To: 3, Weight: 1, Undirected: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSVG(&buf, g, []int64{2})
+	if err != nil {
+		t.Fatalf("WriteSVG() error = %v", err)
+	}
+
+	var svg struct {
+		ViewBox string `xml:"viewBox,attr"`
+		Lines   []struct {
+			Stroke string `xml:"stroke,attr"`
+			Marker string `xml:"marker-end,attr"`
+		} `xml:"line"`
+		Circles []struct{} `xml:"circle"`
+		Texts   []string   `xml:"text"`
+	}
+
+	err = xml.Unmarshal(buf.Bytes(), &svg)
+	if err != nil {
+		t.Fatalf("WriteSVG() wrote invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if svg.ViewBox != "-40 -40 180 180" {
+		t.Errorf("WriteSVG() viewBox = %s, want -40 -40 180 180",
+			svg.ViewBox)
+	}
+	if len(svg.Lines) != 2 {
+		t.Fatalf("WriteSVG() lines = %d, want 2", len(svg.Lines))
+	}
+	if svg.Lines[0].Marker != "url(#arrow)" {
+		t.Errorf("WriteSVG() directed edge marker = %q", svg.Lines[0].Marker)
+	}
+	if svg.Lines[1].Stroke != svgHighlightColor || svg.Lines[1].Marker != "" {
+		t.Errorf("WriteSVG() highlighted edge = %+v", svg.Lines[1])
+	}
+	// Three vertexes and the loop.
+	if len(svg.Circles) != 4 {
+		t.Errorf("WriteSVG() circles = %d, want 4", len(svg.Circles))
+	}
+	if len(svg.Texts) != 6 || svg.Texts[3] != "<a>" {
+		t.Errorf("WriteSVG() texts = %q", svg.Texts)
+	}
+}
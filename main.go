@@ -3,28 +3,72 @@ package main
 import (
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/dimuls/graph/memory"
 	"github.com/dimuls/graph/postgres"
+	"github.com/dimuls/graph/sqlite"
 	"github.com/dimuls/graph/web"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	postgresURI := os.Getenv("POSTGRES_URI")
+	// POSTGRES_URI is supported for compatibility with old deployments.
+	storageURI := os.Getenv("STORAGE_URI")
+	if storageURI == "" {
+		storageURI = os.Getenv("POSTGRES_URI")
+	}
 	bindAddr := os.Getenv("BIND_ADDR")
 
-	storage, err := postgres.NewStorage(postgresURI)
-	if err != nil {
-		logrus.WithError(err).Fatal(
-			"failed to create new postgres storage")
-	}
+	var storage web.Storage
+
+	switch {
+	case storageURI == "":
+		logrus.Warn("STORAGE_URI is not set, using in-memory storage, " +
+			"data will be lost on exit")
+
+		storage = memory.NewStorage()
+
+	case strings.HasPrefix(storageURI, "postgres://"),
+		strings.HasPrefix(storageURI, "postgresql://"):
+
+		pgStorage, err := postgres.NewStorage(storageURI)
+		if err != nil {
+			logrus.WithError(err).Fatal(
+				"failed to create new postgres storage")
+		}
+
+		err = pgStorage.Migrate()
+		if err != nil {
+			logrus.WithError(err).Fatal(
+				"failed to migrate postgres storage")
+		}
+
+		storage = pgStorage
+
+	case strings.HasPrefix(storageURI, "file://"):
+		// file:///var/lib/graph.db is absolute path and file://graph.db is
+		// relative one.
+		sqliteStorage, err := sqlite.NewStorage(
+			strings.TrimPrefix(storageURI, "file://"))
+		if err != nil {
+			logrus.WithError(err).Fatal(
+				"failed to create new sqlite storage")
+		}
+
+		err = sqliteStorage.Migrate()
+		if err != nil {
+			logrus.WithError(err).Fatal(
+				"failed to migrate sqlite storage")
+		}
+
+		storage = sqliteStorage
 
-	err = storage.Migrate()
-	if err != nil {
-		logrus.WithError(err).Fatal(
-			"failed to migrate postgres storage")
+	default:
+		logrus.Fatal("unsupported STORAGE_URI scheme, want postgres:// " +
+			"or file://")
 	}
 
 	webServer := web.NewServer(bindAddr, storage)
@@ -36,7 +80,7 @@ func main() {
 
 	s := <-ss
 
-	logrus.Info("captured %v signal, stopping", s)
+	logrus.Infof("captured %v signal, stopping", s)
 
 	st := time.Now()
 	webServer.Stop()
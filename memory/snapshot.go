@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func (s *Storage) Snapshots(graphID int64) (ss []entity.Snapshot, err error) {
+	s.read(func(d *data) {
+		for _, snap := range d.snapshots {
+			if snap.GraphID == graphID {
+				ss = append(ss, snap.Snapshot)
+			}
+		}
+	})
+	sort.Slice(ss, func(i, j int) bool {
+		if !ss[i].CreatedAt.Equal(ss[j].CreatedAt) {
+			return ss[i].CreatedAt.After(ss[j].CreatedAt)
+		}
+		return ss[i].ID > ss[j].ID
+	})
+	return
+}
+
+func (s *Storage) AddSnapshot(graphID int64, name string) (
+	ss entity.Snapshot, err error) {
+
+	err = s.write(func(ts *Storage) error {
+		d := &ts.st.data
+
+		if _, exists := d.graphs[graphID]; !exists {
+			return entity.ErrGraphNotFound
+		}
+
+		for _, snap := range d.snapshots {
+			if snap.GraphID == graphID && snap.Name == name {
+				return entity.ErrDuplicatedSnapshotName
+			}
+		}
+
+		ts.st.seq.snapshot++
+
+		snap := snapshot{
+			vertexes: d.graphVertexes(graphID),
+			edges:    d.graphEdges(graphID),
+		}
+		snap.Snapshot = entity.Snapshot{
+			ID:          ts.st.seq.snapshot,
+			GraphID:     graphID,
+			Name:        name,
+			VertexCount: len(snap.vertexes),
+			EdgeCount:   len(snap.edges),
+			CreatedAt:   time.Now(),
+		}
+
+		ts.putSnapshot(snap)
+		ss = snap.Snapshot
+
+		return nil
+	})
+	return
+}
+
+func (s *Storage) RestoreSnapshot(graphID int64, snapshotID int64) error {
+	return s.write(func(ts *Storage) error {
+		return ts.restoreSnapshot(graphID, snapshotID)
+	})
+}
+
+func (s *Storage) restoreSnapshot(graphID int64, snapshotID int64) error {
+	d := &s.st.data
+
+	if _, exists := d.graphs[graphID]; !exists {
+		return entity.ErrGraphNotFound
+	}
+
+	snap, exists := d.snapshots[snapshotID]
+	if !exists || snap.GraphID != graphID {
+		return entity.ErrSnapshotNotFound
+	}
+
+	vertexIDs := make(map[int64]bool, len(snap.vertexes))
+	for _, v := range snap.vertexes {
+		vertexIDs[v.ID] = true
+	}
+
+	edgeIDs := make(map[int64]bool, len(snap.edges))
+	for _, e := range snap.edges {
+		edgeIDs[e.ID] = true
+	}
+
+	// Vertexes and edges which are in the snapshot keep their IDs and get
+	// new versions if changed, others are removed or restored.
+	for _, e := range d.graphEdges(graphID) {
+		if !edgeIDs[e.ID] {
+			err := s.removeEdge(e.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range d.graphVertexes(graphID) {
+		if !vertexIDs[v.ID] {
+			err := s.removeVertex(v.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range snap.vertexes {
+		old, exists := d.vertexes[v.ID]
+		if !exists {
+			err := s.insertVertex(v)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if old.X != v.X || old.Y != v.Y || old.Label != v.Label ||
+			!reflect.DeepEqual(old.Attributes, v.Attributes) {
+
+			err := s.updateVertex(old, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range snap.edges {
+		old, exists := d.edges[e.ID]
+		if !exists {
+			err := s.insertEdge(e)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if old.Weight != e.Weight || old.Undirected != e.Undirected ||
+			old.Label != e.Label ||
+			!reflect.DeepEqual(old.Attributes, e.Attributes) {
+
+			err := s.updateEdge(old, e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveSnapshot(graphID int64, snapshotID int64) error {
+	return s.write(func(ts *Storage) error {
+		d := &ts.st.data
+
+		snap, exists := d.snapshots[snapshotID]
+		if !exists || snap.GraphID != graphID {
+			return entity.ErrSnapshotNotFound
+		}
+
+		ts.deleteSnapshot(snapshotID)
+
+		return nil
+	})
+}
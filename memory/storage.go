@@ -0,0 +1,934 @@
+// Package memory implements web.Storage which keeps graphs in memory. It is
+// meant for tests and demo mode: nothing is persisted and transactions
+// hold the lock of the whole storage.
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+)
+
+// historyStep is the undo step of the graph, see history_step table of
+// postgres storage.
+type historyStep struct {
+	id     int64
+	txID   int64
+	undone bool
+}
+
+type snapshot struct {
+	entity.Snapshot
+	vertexes []entity.Vertex
+	edges    []entity.Edge
+}
+
+// data is the state of the storage. Stored values are never changed in
+// place, they are replaced through put and delete methods of Storage which
+// log how to roll the change back.
+type data struct {
+	graphs    map[int64]entity.Graph
+	vertexes  map[int64]entity.Vertex
+	edges     map[int64]entity.Edge
+	snapshots map[int64]snapshot
+
+	// changes is append only.
+	changes []entity.Change
+
+	// steps maps graph IDs to their undo steps in the order of creation.
+	// Slices are replaced on changes of their elements.
+	steps map[int64][]historyStep
+}
+
+// sequences generate IDs. Like postgres sequences they are not rolled
+// back.
+type sequences struct {
+	graph    int64
+	vertex   int64
+	edge     int64
+	snapshot int64
+	change   int64
+	step     int64
+	tx       int64
+}
+
+// state is shared by the storage and its transactions.
+type state struct {
+	mx   sync.RWMutex
+	data data
+	seq  sequences
+}
+
+// tx is the write transaction which holds the write lock of the state.
+type tx struct {
+	id        int64
+	startedAt time.Time
+
+	// history is set by undo and redo which manage undo steps themselves.
+	history bool
+
+	// readOnly is set for read transactions which hold the read lock.
+	readOnly bool
+
+	// undo reverts changes of the transaction in the reverse order.
+	undo []func(d *data)
+}
+
+// errReadOnlyTx is returned by mutations in read transactions.
+var errReadOnlyTx = errors.New("read-only transaction")
+
+type Storage struct {
+	st    *state
+	tx    *tx
+	actor string
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		st: &state{
+			data: data{
+				graphs:    map[int64]entity.Graph{},
+				vertexes:  map[int64]entity.Vertex{},
+				edges:     map[int64]entity.Edge{},
+				snapshots: map[int64]snapshot{},
+				steps:     map[int64][]historyStep{},
+			},
+		},
+	}
+}
+
+// read calls f with the state locked for reading unless the storage is in
+// transaction which already holds the lock.
+func (s *Storage) read(f func(d *data)) {
+	if s.tx == nil {
+		s.st.mx.RLock()
+		defer s.st.mx.RUnlock()
+	}
+	f(&s.st.data)
+}
+
+// write calls f with storage in transaction. The new transaction is
+// started unless the storage is already in one, it is committed if f
+// returns nil and rolled back otherwise.
+func (s *Storage) write(f func(ts *Storage) error) (err error) {
+	if s.tx != nil {
+		if s.tx.readOnly {
+			return errReadOnlyTx
+		}
+		return f(s)
+	}
+
+	s.st.mx.Lock()
+	defer s.st.mx.Unlock()
+
+	s.st.seq.tx++
+
+	t := &tx{id: s.st.seq.tx, startedAt: time.Now()}
+	committed := false
+
+	defer func() {
+		if !committed {
+			for i := len(t.undo) - 1; i >= 0; i-- {
+				t.undo[i](&s.st.data)
+			}
+		}
+	}()
+
+	err = f(&Storage{
+		st:    s.st,
+		tx:    t,
+		actor: s.actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	committed = true
+
+	return nil
+}
+
+// InTx calls f with storage which applies all changes atomically. Nested
+// calls run in the outer transaction.
+func (s *Storage) InTx(f func(s web.Storage) error) error {
+	return s.write(func(ts *Storage) error {
+		return f(ts)
+	})
+}
+
+// onRollback adds f to the undo log of the transaction.
+func (s *Storage) onRollback(f func(d *data)) {
+	s.tx.undo = append(s.tx.undo, f)
+}
+
+func (s *Storage) putGraph(g entity.Graph) {
+	d := &s.st.data
+	old, exists := d.graphs[g.ID]
+	s.onRollback(func(d *data) {
+		if exists {
+			d.graphs[g.ID] = old
+		} else {
+			delete(d.graphs, g.ID)
+		}
+	})
+	d.graphs[g.ID] = g
+}
+
+func (s *Storage) deleteGraph(graphID int64) {
+	d := &s.st.data
+	old, exists := d.graphs[graphID]
+	if !exists {
+		return
+	}
+	s.onRollback(func(d *data) {
+		d.graphs[graphID] = old
+	})
+	delete(d.graphs, graphID)
+}
+
+func (s *Storage) putVertex(v entity.Vertex) {
+	d := &s.st.data
+	old, exists := d.vertexes[v.ID]
+	s.onRollback(func(d *data) {
+		if exists {
+			d.vertexes[v.ID] = old
+		} else {
+			delete(d.vertexes, v.ID)
+		}
+	})
+	d.vertexes[v.ID] = v
+}
+
+func (s *Storage) deleteVertex(vertexID int64) {
+	d := &s.st.data
+	old, exists := d.vertexes[vertexID]
+	if !exists {
+		return
+	}
+	s.onRollback(func(d *data) {
+		d.vertexes[vertexID] = old
+	})
+	delete(d.vertexes, vertexID)
+}
+
+func (s *Storage) putEdge(e entity.Edge) {
+	d := &s.st.data
+	old, exists := d.edges[e.ID]
+	s.onRollback(func(d *data) {
+		if exists {
+			d.edges[e.ID] = old
+		} else {
+			delete(d.edges, e.ID)
+		}
+	})
+	d.edges[e.ID] = e
+}
+
+func (s *Storage) deleteEdge(edgeID int64) {
+	d := &s.st.data
+	old, exists := d.edges[edgeID]
+	if !exists {
+		return
+	}
+	s.onRollback(func(d *data) {
+		d.edges[edgeID] = old
+	})
+	delete(d.edges, edgeID)
+}
+
+func (s *Storage) putSnapshot(ss snapshot) {
+	d := &s.st.data
+	old, exists := d.snapshots[ss.ID]
+	s.onRollback(func(d *data) {
+		if exists {
+			d.snapshots[ss.ID] = old
+		} else {
+			delete(d.snapshots, ss.ID)
+		}
+	})
+	d.snapshots[ss.ID] = ss
+}
+
+func (s *Storage) deleteSnapshot(snapshotID int64) {
+	d := &s.st.data
+	old, exists := d.snapshots[snapshotID]
+	if !exists {
+		return
+	}
+	s.onRollback(func(d *data) {
+		d.snapshots[snapshotID] = old
+	})
+	delete(d.snapshots, snapshotID)
+}
+
+func (s *Storage) putSteps(graphID int64, steps []historyStep) {
+	d := &s.st.data
+	old, exists := d.steps[graphID]
+	s.onRollback(func(d *data) {
+		if exists {
+			d.steps[graphID] = old
+		} else {
+			delete(d.steps, graphID)
+		}
+	})
+	d.steps[graphID] = steps
+}
+
+func (s *Storage) appendChange(c entity.Change) {
+	d := &s.st.data
+	n := len(d.changes)
+	s.onRollback(func(d *data) {
+		d.changes = d.changes[:n]
+	})
+	d.changes = append(d.changes, c)
+}
+
+// InReadTx calls f with storage which sees the state as of the start of
+// the transaction. Writers wait for f to return.
+func (s *Storage) InReadTx(f func(s web.Storage) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	s.st.mx.RLock()
+	defer s.st.mx.RUnlock()
+
+	return f(&Storage{
+		st:    s.st,
+		tx:    &tx{readOnly: true},
+		actor: s.actor,
+	})
+}
+
+// WithActor returns storage which records actor as the author of changes
+// in the change log.
+func (s *Storage) WithActor(actor string) web.Storage {
+	s2 := *s
+	s2.actor = actor
+	return &s2
+}
+
+// normalizeAttributes returns the copy of attributes as they are stored
+// in JSONB column: numbers become float64 and nil becomes empty map.
+func normalizeAttributes(as entity.Attributes) (entity.Attributes, error) {
+	data, err := json.Marshal(as)
+	if err != nil {
+		return nil, err
+	}
+
+	var res entity.Attributes
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		res = entity.Attributes{}
+	}
+
+	return res, nil
+}
+
+// copyValue returns the deep copy of the normalized attribute value.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			c[k] = copyValue(vv)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, vv := range v {
+			c[i] = copyValue(vv)
+		}
+		return c
+	}
+	return v
+}
+
+// copyAttributes returns the deep copy of stored attributes, so callers
+// can not change them.
+func copyAttributes(as entity.Attributes) entity.Attributes {
+	if as == nil {
+		return nil
+	}
+	c := copyValue(map[string]interface{}(as))
+	return entity.Attributes(c.(map[string]interface{}))
+}
+
+func copyVertex(v entity.Vertex) entity.Vertex {
+	v.Attributes = copyAttributes(v.Attributes)
+	return v
+}
+
+func copyEdge(e entity.Edge) entity.Edge {
+	e.Attributes = copyAttributes(e.Attributes)
+	return e
+}
+
+// logChange records the change to the change log and makes the undo step
+// like log_change trigger of postgres storage.
+func (s *Storage) logChange(entityName string, graphID int64, entityID int64,
+	operation string, before interface{}, after interface{}) error {
+
+	d := &s.st.data
+
+	if entityName != entity.EntityGraph && !s.tx.history {
+		// Undone steps are always the last ones.
+		steps := d.steps[graphID]
+		n := len(steps)
+		for n > 0 && steps[n-1].undone {
+			n--
+		}
+		steps = steps[:n]
+
+		if n == 0 || steps[n-1].txID != s.tx.id {
+			s.st.seq.step++
+			steps = append(steps[:n:n], historyStep{
+				id:   s.st.seq.step,
+				txID: s.tx.id,
+			})
+		}
+
+		s.putSteps(graphID, steps)
+	}
+
+	c := entity.Change{
+		GraphID:   graphID,
+		Entity:    entityName,
+		EntityID:  entityID,
+		Operation: operation,
+		Actor:     s.actor,
+		CreatedAt: s.tx.startedAt,
+		TxID:      s.tx.id,
+	}
+
+	var err error
+
+	if before != nil {
+		c.Before, err = json.Marshal(before)
+		if err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		c.After, err = json.Marshal(after)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.st.seq.change++
+	c.ID = s.st.seq.change
+
+	s.appendChange(c)
+
+	return nil
+}
+
+func (s *Storage) Graph(graphID int64) (g entity.Graph, err error) {
+	s.read(func(d *data) {
+		var exists bool
+		g, exists = d.graphs[graphID]
+		if !exists {
+			err = entity.ErrGraphNotFound
+		}
+	})
+	return
+}
+
+func (s *Storage) Graphs() (gs []entity.Graph, err error) {
+	s.read(func(d *data) {
+		for _, g := range d.graphs {
+			gs = append(gs, g)
+		}
+	})
+	sort.Slice(gs, func(i, j int) bool {
+		return gs[i].Name < gs[j].Name
+	})
+	return
+}
+
+// graphNameExists reports whether the graph with the given name exists.
+func (d *data) graphNameExists(name string) bool {
+	for _, g := range d.graphs {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) AddGraph(g entity.Graph) (id int64, err error) {
+	err = s.write(func(ts *Storage) (err error) {
+		id, err = ts.addGraph(g)
+		return
+	})
+	return
+}
+
+func (s *Storage) addGraph(g entity.Graph) (int64, error) {
+	d := &s.st.data
+
+	if d.graphNameExists(g.Name) {
+		return 0, entity.ErrDuplicatedGraphName
+	}
+
+	s.st.seq.graph++
+
+	g = entity.Graph{
+		ID:       s.st.seq.graph,
+		Name:     g.Name,
+		Directed: g.Directed,
+		Version:  1,
+	}
+
+	s.putGraph(g)
+
+	return g.ID, s.logChange(entity.EntityGraph, g.ID, g.ID,
+		entity.OperationInsert, nil, g)
+}
+
+// CloneGraph copies the graph with its vertexes and edges into the new
+// graph with the given name. Copied vertexes and edges get new IDs.
+func (s *Storage) CloneGraph(graphID int64, name string) (id int64,
+	err error) {
+
+	err = s.write(func(ts *Storage) (err error) {
+		id, err = ts.cloneGraph(graphID, name)
+		return
+	})
+	return
+}
+
+func (s *Storage) cloneGraph(graphID int64, name string) (int64, error) {
+	d := &s.st.data
+
+	g, exists := d.graphs[graphID]
+	if !exists {
+		return 0, entity.ErrGraphNotFound
+	}
+
+	g.Name = name
+
+	id, err := s.addGraph(g)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := map[int64]int64{}
+
+	for _, v := range d.graphVertexes(graphID) {
+		oldID := v.ID
+		v.GraphID = id
+		v.ID, err = s.addVertex(v)
+		if err != nil {
+			return 0, err
+		}
+		ids[oldID] = v.ID
+	}
+
+	for _, e := range d.graphEdges(graphID) {
+		from, fromExists := ids[e.From]
+		to, toExists := ids[e.To]
+		if !fromExists || !toExists {
+			continue
+		}
+		e.GraphID, e.From, e.To = id, from, to
+		_, err = s.addEdge(e)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// RemoveGraph removes the graph with its vertexes, edges and snapshots.
+func (s *Storage) RemoveGraph(graphID int64) error {
+	return s.write(func(ts *Storage) error {
+		return ts.removeGraph(graphID)
+	})
+}
+
+func (s *Storage) removeGraph(graphID int64) error {
+	d := &s.st.data
+
+	g, exists := d.graphs[graphID]
+	if !exists {
+		return nil
+	}
+
+	for _, e := range d.graphEdges(graphID) {
+		err := s.removeEdge(e.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, v := range d.graphVertexes(graphID) {
+		err := s.removeVertex(v.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, ss := range d.snapshots {
+		if ss.GraphID == graphID {
+			s.deleteSnapshot(id)
+		}
+	}
+
+	s.deleteGraph(graphID)
+
+	return s.logChange(entity.EntityGraph, graphID, graphID,
+		entity.OperationDelete, g, nil)
+}
+
+func (s *Storage) Vertex(vertexID int64) (v entity.Vertex, err error) {
+	s.read(func(d *data) {
+		var exists bool
+		v, exists = d.vertexes[vertexID]
+		if !exists {
+			err = entity.ErrVertexNotFound
+			return
+		}
+		v = copyVertex(v)
+	})
+	return
+}
+
+// graphVertexes returns stored vertexes of the graph ordered by ID.
+func (d *data) graphVertexes(graphID int64) (vs []entity.Vertex) {
+	for _, v := range d.vertexes {
+		if v.GraphID == graphID {
+			vs = append(vs, v)
+		}
+	}
+	sort.Slice(vs, func(i, j int) bool {
+		return vs[i].ID < vs[j].ID
+	})
+	return
+}
+
+func (s *Storage) Vertexes(graphID int64) (vs []entity.Vertex, err error) {
+	s.read(func(d *data) {
+		vs = d.graphVertexes(graphID)
+	})
+	for i := range vs {
+		vs[i] = copyVertex(vs[i])
+	}
+	return
+}
+
+func (s *Storage) AddVertex(v entity.Vertex) (id int64, err error) {
+	err = s.write(func(ts *Storage) (err error) {
+		id, err = ts.addVertex(v)
+		return
+	})
+	return
+}
+
+func (s *Storage) addVertex(v entity.Vertex) (int64, error) {
+	d := &s.st.data
+
+	if _, exists := d.graphs[v.GraphID]; !exists {
+		return 0, entity.ErrGraphNotFound
+	}
+
+	as, err := normalizeAttributes(v.Attributes)
+	if err != nil {
+		return 0, err
+	}
+
+	s.st.seq.vertex++
+
+	v = entity.Vertex{
+		ID:         s.st.seq.vertex,
+		GraphID:    v.GraphID,
+		X:          v.X,
+		Y:          v.Y,
+		Label:      v.Label,
+		Attributes: as,
+		Version:    1,
+	}
+
+	return v.ID, s.insertVertex(v)
+}
+
+// insertVertex stores the vertex as is.
+func (s *Storage) insertVertex(v entity.Vertex) error {
+	d := &s.st.data
+
+	if _, exists := d.graphs[v.GraphID]; !exists {
+		return entity.ErrGraphNotFound
+	}
+
+	s.putVertex(v)
+
+	return s.logChange(entity.EntityVertex, v.GraphID, v.ID,
+		entity.OperationInsert, nil, v)
+}
+
+func (s *Storage) SetVertex(v entity.Vertex) (version int64, err error) {
+	err = s.write(func(ts *Storage) (err error) {
+		version, err = ts.setVertex(v)
+		return
+	})
+	return
+}
+
+func (s *Storage) setVertex(v entity.Vertex) (int64, error) {
+	d := &s.st.data
+
+	old, exists := d.vertexes[v.ID]
+	if !exists {
+		return 0, entity.ErrVertexNotFound
+	}
+
+	if v.Version != 0 && v.Version != old.Version {
+		return 0, entity.ErrVersionConflict
+	}
+
+	return old.Version + 1, s.updateVertex(old, v)
+}
+
+// updateVertex updates the stored vertex old with coordinates, label and
+// attributes of v and increments its version.
+func (s *Storage) updateVertex(old entity.Vertex, v entity.Vertex) error {
+	as, err := normalizeAttributes(v.Attributes)
+	if err != nil {
+		return err
+	}
+
+	updated := old
+	updated.X, updated.Y = v.X, v.Y
+	updated.Label = v.Label
+	updated.Attributes = as
+	updated.Version++
+
+	s.putVertex(updated)
+
+	return s.logChange(entity.EntityVertex, old.GraphID, old.ID,
+		entity.OperationUpdate, old, updated)
+}
+
+// RemoveVertex removes the vertex with all its edges.
+func (s *Storage) RemoveVertex(vertexID int64) error {
+	return s.write(func(ts *Storage) error {
+		return ts.removeVertex(vertexID)
+	})
+}
+
+func (s *Storage) removeVertex(vertexID int64) error {
+	d := &s.st.data
+
+	v, exists := d.vertexes[vertexID]
+	if !exists {
+		return nil
+	}
+
+	var es []entity.Edge
+	for _, e := range d.edges {
+		if e.From == vertexID || e.To == vertexID {
+			es = append(es, e)
+		}
+	}
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].ID < es[j].ID
+	})
+
+	for _, e := range es {
+		err := s.removeEdge(e.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.deleteVertex(vertexID)
+
+	return s.logChange(entity.EntityVertex, v.GraphID, v.ID,
+		entity.OperationDelete, v, nil)
+}
+
+func (s *Storage) Edge(edgeID int64) (e entity.Edge, err error) {
+	s.read(func(d *data) {
+		var exists bool
+		e, exists = d.edges[edgeID]
+		if !exists {
+			err = entity.ErrEdgeNotFound
+			return
+		}
+		e = copyEdge(e)
+	})
+	return
+}
+
+// graphEdges returns stored edges of the graph ordered by ID.
+func (d *data) graphEdges(graphID int64) (es []entity.Edge) {
+	for _, e := range d.edges {
+		if e.GraphID == graphID {
+			es = append(es, e)
+		}
+	}
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].ID < es[j].ID
+	})
+	return
+}
+
+func (s *Storage) Edges(graphID int64) (es []entity.Edge, err error) {
+	s.read(func(d *data) {
+		es = d.graphEdges(graphID)
+	})
+	for i := range es {
+		es[i] = copyEdge(es[i])
+	}
+	return
+}
+
+func (s *Storage) AddEdge(e entity.Edge) (id int64, err error) {
+	err = s.write(func(ts *Storage) (err error) {
+		id, err = ts.addEdge(e)
+		return
+	})
+	return
+}
+
+func (s *Storage) addEdge(e entity.Edge) (int64, error) {
+	as, err := normalizeAttributes(e.Attributes)
+	if err != nil {
+		return 0, err
+	}
+
+	s.st.seq.edge++
+
+	e = entity.Edge{
+		ID:         s.st.seq.edge,
+		GraphID:    e.GraphID,
+		From:       e.From,
+		To:         e.To,
+		Weight:     e.Weight,
+		Undirected: e.Undirected,
+		Label:      e.Label,
+		Attributes: as,
+		Version:    1,
+	}
+
+	return e.ID, s.insertEdge(e)
+}
+
+// insertEdge stores the edge as is.
+func (s *Storage) insertEdge(e entity.Edge) error {
+	d := &s.st.data
+
+	if _, exists := d.graphs[e.GraphID]; !exists {
+		return entity.ErrGraphNotFound
+	}
+	if _, exists := d.vertexes[e.From]; !exists {
+		return entity.ErrVertexNotFound
+	}
+	if _, exists := d.vertexes[e.To]; !exists {
+		return entity.ErrVertexNotFound
+	}
+
+	s.putEdge(e)
+
+	return s.logChange(entity.EntityEdge, e.GraphID, e.ID,
+		entity.OperationInsert, nil, e)
+}
+
+func (s *Storage) SetEdge(e entity.Edge) (version int64, err error) {
+	err = s.write(func(ts *Storage) (err error) {
+		version, err = ts.setEdge(e)
+		return
+	})
+	return
+}
+
+func (s *Storage) setEdge(e entity.Edge) (int64, error) {
+	old, exists := s.st.data.edges[e.ID]
+	if !exists {
+		return 0, entity.ErrEdgeNotFound
+	}
+
+	if e.Version != 0 && e.Version != old.Version {
+		return 0, entity.ErrVersionConflict
+	}
+
+	return old.Version + 1, s.updateEdge(old, e)
+}
+
+// updateEdge updates the stored edge old with weight, direction, label and
+// attributes of e and increments its version.
+func (s *Storage) updateEdge(old entity.Edge, e entity.Edge) error {
+	as, err := normalizeAttributes(e.Attributes)
+	if err != nil {
+		return err
+	}
+
+	updated := old
+	updated.Weight = e.Weight
+	updated.Undirected = e.Undirected
+	updated.Label = e.Label
+	updated.Attributes = as
+	updated.Version++
+
+	s.putEdge(updated)
+
+	return s.logChange(entity.EntityEdge, old.GraphID, old.ID,
+		entity.OperationUpdate, old, updated)
+}
+
+func (s *Storage) RemoveEdge(edgeID int64) error {
+	return s.write(func(ts *Storage) error {
+		return ts.removeEdge(edgeID)
+	})
+}
+
+func (s *Storage) removeEdge(edgeID int64) error {
+	d := &s.st.data
+
+	e, exists := d.edges[edgeID]
+	if !exists {
+		return nil
+	}
+
+	s.deleteEdge(edgeID)
+
+	return s.logChange(entity.EntityEdge, e.GraphID, e.ID,
+		entity.OperationDelete, e, nil)
+}
+
+func (s *Storage) Changes(graphID int64, f entity.ChangeFilter) (
+	cs []entity.Change, err error) {
+
+	s.read(func(d *data) {
+		for i := len(d.changes) - 1; i >= 0; i-- {
+			if f.Limit > 0 && len(cs) == f.Limit {
+				break
+			}
+
+			c := d.changes[i]
+
+			if c.GraphID != graphID ||
+				(f.Entity != "" && c.Entity != f.Entity) ||
+				(f.EntityID != 0 && c.EntityID != f.EntityID) ||
+				(!f.Since.IsZero() && c.CreatedAt.Before(f.Since)) ||
+				(!f.Until.IsZero() && !c.CreatedAt.Before(f.Until)) ||
+				(f.BeforeID != 0 && c.ID >= f.BeforeID) {
+				continue
+			}
+
+			cs = append(cs, c)
+		}
+	})
+	return
+}
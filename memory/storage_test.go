@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/storagetest"
+	"github.com/dimuls/graph/web"
+)
+
+func TestStorage_InTx_rollback(t *testing.T) {
+	s := NewStorage()
+
+	graphID, err := s.AddGraph(entity.Graph{Name: "g"})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+
+	errTest := errors.New("test")
+
+	err = s.InTx(func(ts web.Storage) error {
+		_, err := ts.AddVertex(entity.Vertex{GraphID: graphID})
+		if err != nil {
+			return err
+		}
+		err = ts.RemoveGraph(graphID)
+		if err != nil {
+			return err
+		}
+		return errTest
+	})
+	if err != errTest {
+		t.Fatalf("InTx() error = %v, want %v", err, errTest)
+	}
+
+	_, err = s.Graph(graphID)
+	if err != nil {
+		t.Errorf("Graph() error = %v", err)
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		t.Fatalf("Vertexes() error = %v", err)
+	}
+	if len(vs) != 0 {
+		t.Errorf("Vertexes() got = %+v, want none", vs)
+	}
+
+	cs, err := s.Changes(graphID, entity.ChangeFilter{})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(cs) != 1 || cs[0].Entity != entity.EntityGraph {
+		t.Errorf("Changes() got = %+v, want graph insert", cs)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != entity.ErrNothingToUndo {
+		t.Errorf("Undo() error = %v, want %v", err, entity.ErrNothingToUndo)
+	}
+}
+
+func TestStorage_RemoveVertex_cascade(t *testing.T) {
+	s := NewStorage()
+
+	graphID, _ := s.AddGraph(entity.Graph{Name: "g"})
+	v1, _ := s.AddVertex(entity.Vertex{GraphID: graphID})
+	v2, _ := s.AddVertex(entity.Vertex{GraphID: graphID})
+	e, err := s.AddEdge(entity.Edge{GraphID: graphID, From: v1, To: v2})
+	if err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+
+	err = s.RemoveVertex(v2)
+	if err != nil {
+		t.Fatalf("RemoveVertex() error = %v", err)
+	}
+
+	_, err = s.Edge(e)
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("Edge() error = %v, want %v", err, entity.ErrEdgeNotFound)
+	}
+
+	_, err = s.AddEdge(entity.Edge{GraphID: graphID, From: v1, To: v2})
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("AddEdge() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+
+	err = s.RemoveGraph(graphID)
+	if err != nil {
+		t.Fatalf("RemoveGraph() error = %v", err)
+	}
+
+	_, err = s.Vertex(v1)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+}
+
+func TestStorage_attributes(t *testing.T) {
+	s := NewStorage()
+
+	graphID, _ := s.AddGraph(entity.Graph{Name: "g"})
+	id, _ := s.AddVertex(entity.Vertex{
+		GraphID:    graphID,
+		Attributes: entity.Attributes{"n": 1, "tags": []interface{}{"a"}},
+	})
+
+	v, err := s.Vertex(id)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+
+	want := entity.Attributes{"n": 1.0, "tags": []interface{}{"a"}}
+	if !reflect.DeepEqual(v.Attributes, want) {
+		t.Fatalf("Vertex() attributes = %#v, want %#v", v.Attributes, want)
+	}
+
+	v.Attributes["tags"].([]interface{})[0] = "b"
+
+	v, _ = s.Vertex(id)
+	if !reflect.DeepEqual(v.Attributes, want) {
+		t.Errorf("Vertex() attributes = %#v after change of returned "+
+			"ones, want %#v", v.Attributes, want)
+	}
+}
+
+func TestStorage_conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) web.Storage {
+		return NewStorage()
+	})
+}
@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/dimuls/graph/entity"
+)
+
+func (s *Storage) Undo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, true)
+}
+
+func (s *Storage) Redo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, false)
+}
+
+type stateKey struct {
+	entity string
+	id     int64
+}
+
+// entityState is the state of vertex or edge to restore. Nil data means
+// that the entity should not exist.
+type entityState struct {
+	stateKey
+	data json.RawMessage
+}
+
+// step undoes or redoes the history step of the graph.
+func (s *Storage) step(graphID int64, undo bool) (cs []entity.Change,
+	err error) {
+
+	err = s.write(func(ts *Storage) (err error) {
+		cs, err = ts.applyStep(graphID, undo)
+		return
+	})
+	return
+}
+
+func (s *Storage) applyStep(graphID int64, undo bool) ([]entity.Change,
+	error) {
+
+	d := &s.st.data
+
+	if _, exists := d.graphs[graphID]; !exists {
+		return nil, entity.ErrGraphNotFound
+	}
+
+	steps := d.steps[graphID]
+
+	// Undone steps are always the last ones, so undo takes the last not
+	// undone step and redo takes the first undone one.
+	i := len(steps)
+	for i > 0 && steps[i-1].undone {
+		i--
+	}
+	if undo {
+		if i == 0 {
+			return nil, entity.ErrNothingToUndo
+		}
+		i--
+	} else if i == len(steps) {
+		return nil, entity.ErrNothingToRedo
+	}
+
+	step := steps[i]
+
+	// Changes made in history mode do not make new steps.
+	s.tx.history = true
+
+	// Undo restores the state before the first change of every entity in
+	// the step and redo restores the state after the last one.
+	var (
+		states []*entityState
+		index  = map[stateKey]*entityState{}
+	)
+	for _, c := range d.changes {
+		if c.GraphID != graphID || c.TxID != step.txID ||
+			c.Entity == entity.EntityGraph {
+			continue
+		}
+		key := stateKey{entity: c.Entity, id: c.EntityID}
+		st, exists := index[key]
+		if !exists {
+			st = &entityState{stateKey: key, data: c.Before}
+			index[key] = st
+			states = append(states, st)
+		}
+		if !undo {
+			st.data = c.After
+		}
+	}
+
+	// Edges are deleted before vertexes and restored after them like in
+	// postgres storage.
+	for _, a := range []struct {
+		entity  string
+		deleted bool
+	}{
+		{entity.EntityEdge, true},
+		{entity.EntityVertex, true},
+		{entity.EntityVertex, false},
+		{entity.EntityEdge, false},
+	} {
+		for _, st := range states {
+			if st.entity != a.entity || (st.data == nil) != a.deleted {
+				continue
+			}
+			err := s.restoreState(st)
+			if err != nil {
+				return nil, errors.New("failed to apply step: " +
+					err.Error())
+			}
+		}
+	}
+
+	updated := make([]historyStep, len(steps))
+	copy(updated, steps)
+	updated[i].undone = undo
+	s.putSteps(graphID, updated)
+
+	var cs []entity.Change
+	for _, c := range d.changes {
+		if c.GraphID == graphID && c.TxID == s.tx.id {
+			cs = append(cs, c)
+		}
+	}
+
+	return cs, nil
+}
+
+// restoreState removes the vertex or edge, updates it or inserts it with
+// its original ID.
+func (s *Storage) restoreState(st *entityState) error {
+	d := &s.st.data
+
+	switch st.entity {
+	case entity.EntityVertex:
+		if st.data == nil {
+			return s.removeVertex(st.id)
+		}
+		var v entity.Vertex
+		err := json.Unmarshal(st.data, &v)
+		if err != nil {
+			return err
+		}
+		if old, exists := d.vertexes[v.ID]; exists {
+			return s.updateVertex(old, v)
+		}
+		return s.insertVertex(v)
+
+	case entity.EntityEdge:
+		if st.data == nil {
+			return s.removeEdge(st.id)
+		}
+		var e entity.Edge
+		err := json.Unmarshal(st.data, &e)
+		if err != nil {
+			return err
+		}
+		if old, exists := d.edges[e.ID]; exists {
+			return s.updateEdge(old, e)
+		}
+		return s.insertEdge(e)
+	}
+
+	return nil
+}
@@ -0,0 +1,2 @@
+ALTER TABLE edge DROP COLUMN directed;
+ALTER TABLE graph DROP COLUMN directed;
@@ -0,0 +1,2 @@
+ALTER TABLE graph ADD COLUMN directed BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE edge ADD COLUMN directed BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,4 @@
+ALTER TABLE edge DROP COLUMN attributes;
+ALTER TABLE edge DROP COLUMN label;
+ALTER TABLE vertex DROP COLUMN attributes;
+ALTER TABLE vertex DROP COLUMN label;
@@ -0,0 +1,4 @@
+ALTER TABLE vertex ADD COLUMN label TEXT NOT NULL DEFAULT '';
+ALTER TABLE vertex ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE edge ADD COLUMN label TEXT NOT NULL DEFAULT '';
+ALTER TABLE edge ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE edge DROP COLUMN version;
+ALTER TABLE vertex DROP COLUMN version;
+ALTER TABLE graph DROP COLUMN version;
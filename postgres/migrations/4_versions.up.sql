@@ -0,0 +1,3 @@
+ALTER TABLE graph ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE vertex ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE edge ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP TRIGGER edge_log_change ON edge;
+DROP TRIGGER vertex_log_change ON vertex;
+DROP TRIGGER graph_log_change ON graph;
+DROP FUNCTION log_change();
+DROP TABLE change;
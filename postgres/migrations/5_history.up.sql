@@ -0,0 +1,56 @@
+CREATE TABLE change (
+    id BIGSERIAL PRIMARY KEY,
+    graph_id BIGINT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    operation TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    tx_id BIGINT NOT NULL DEFAULT txid_current()
+);
+
+CREATE INDEX change_graph_id_id_idx ON change (graph_id, id);
+
+CREATE FUNCTION log_change() RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    row_before JSONB;
+    row_after JSONB;
+    row_graph_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    IF TG_OP <> 'INSERT' THEN
+        row_before := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        row_after := to_jsonb(NEW);
+    END IF;
+
+    IF TG_TABLE_NAME = 'graph' THEN
+        row_graph_id := rec.id;
+    ELSE
+        row_graph_id := (to_jsonb(rec) ->> 'graph_id')::BIGINT;
+    END IF;
+
+    INSERT INTO change (graph_id, entity, entity_id, operation, before,
+        after, actor)
+    VALUES (row_graph_id, TG_TABLE_NAME, rec.id, lower(TG_OP), row_before,
+        row_after, coalesce(current_setting('graph.actor', true), ''));
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER graph_log_change AFTER INSERT OR UPDATE OR DELETE ON graph
+    FOR EACH ROW EXECUTE PROCEDURE log_change();
+CREATE TRIGGER vertex_log_change AFTER INSERT OR UPDATE OR DELETE ON vertex
+    FOR EACH ROW EXECUTE PROCEDURE log_change();
+CREATE TRIGGER edge_log_change AFTER INSERT OR UPDATE OR DELETE ON edge
+    FOR EACH ROW EXECUTE PROCEDURE log_change();
@@ -0,0 +1,36 @@
+CREATE OR REPLACE FUNCTION log_change() RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    row_before JSONB;
+    row_after JSONB;
+    row_graph_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    IF TG_OP <> 'INSERT' THEN
+        row_before := to_jsonb(OLD);
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        row_after := to_jsonb(NEW);
+    END IF;
+
+    IF TG_TABLE_NAME = 'graph' THEN
+        row_graph_id := rec.id;
+    ELSE
+        row_graph_id := (to_jsonb(rec) ->> 'graph_id')::BIGINT;
+    END IF;
+
+    INSERT INTO change (graph_id, entity, entity_id, operation, before,
+        after, actor)
+    VALUES (row_graph_id, TG_TABLE_NAME, rec.id, lower(TG_OP), row_before,
+        row_after, coalesce(current_setting('graph.actor', true), ''));
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE history_step;
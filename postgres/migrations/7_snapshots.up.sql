@@ -0,0 +1,9 @@
+CREATE TABLE snapshot (
+    id BIGSERIAL PRIMARY KEY,
+    graph_id BIGINT NOT NULL REFERENCES graph (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    vertexes JSONB NOT NULL,
+    edges JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (graph_id, name)
+);
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dimuls/graph/web"
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the channel of notifications between instances of the
+// web server.
+const notifyChannel = "graph_events"
+
+// maxNotifyPayload is the limit of NOTIFY payload size in bytes.
+const maxNotifyPayload = 8000
+
+// Notify sends the notification to all instances of the web server which
+// listen this database. Notifications with messages which do not fit into
+// NOTIFY payload are sent without them, so listeners reload the graph.
+//
+// In transaction the notification is delivered by Postgres when the
+// transaction is committed and is discarded if it is rolled back.
+func (s *Storage) Notify(n web.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return errors.New("failed to encode notification: " + err.Error())
+	}
+
+	if len(payload) >= maxNotifyPayload {
+		n.Message = nil
+		payload, err = json.Marshal(n)
+		if err != nil {
+			return errors.New("failed to encode notification: " +
+				err.Error())
+		}
+	}
+
+	_, err = s.q.Exec(`SELECT pg_notify($1, $2)`, notifyChannel,
+		string(payload))
+	if err != nil {
+		return errors.New("failed to notify: " + err.Error())
+	}
+
+	return nil
+}
+
+// Listen calls f with notifications sent by Notify until stop is closed.
+// The connection is restored automatically, f is called with zero
+// notification after that since notifications could be lost meanwhile.
+func (s *Storage) Listen(stop <-chan struct{},
+	f func(n web.Notification)) error {
+
+	l := pq.NewListener(s.uri, time.Second, time.Minute, nil)
+	defer l.Close()
+
+	err := l.Listen(notifyChannel)
+	if err != nil {
+		return errors.New("failed to listen: " + err.Error())
+	}
+
+	// Ping detects broken connections when there are no notifications.
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+
+		case pn := <-l.Notify:
+			if pn == nil {
+				f(web.Notification{})
+				continue
+			}
+
+			// Payloads not sent by Notify, e.g. by manual NOTIFY, are
+			// skipped.
+			var n web.Notification
+			err = json.Unmarshal([]byte(pn.Extra), &n)
+			if err != nil {
+				s.log.WithError(err).WithField("payload", pn.Extra).
+					Error("failed to decode notification")
+				continue
+			}
+
+			f(n)
+
+		case <-ping.C:
+			go l.Ping()
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+	"github.com/lib/pq"
+)
+
+const snapshotColumns = `
+	id, graph_id, name, jsonb_array_length(vertexes) AS vertex_count,
+	jsonb_array_length(edges) AS edge_count, created_at
+`
+
+func (s *Storage) Snapshots(graphID int64) (ss []entity.Snapshot, err error) {
+	err = s.q.Select(&ss, `
+		SELECT `+snapshotColumns+` FROM snapshot
+		WHERE graph_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, graphID)
+	return
+}
+
+func (s *Storage) AddSnapshot(graphID int64, name string) (
+	ss entity.Snapshot, err error) {
+
+	err = s.q.QueryRowx(`
+		INSERT INTO snapshot (graph_id, name, vertexes, edges)
+		SELECT g.id, $2,
+			(SELECT coalesce(jsonb_agg(v ORDER BY v.id), '[]')
+				FROM vertex v WHERE v.graph_id = g.id),
+			(SELECT coalesce(jsonb_agg(e ORDER BY e.id), '[]')
+				FROM edge e WHERE e.graph_id = g.id)
+		FROM graph g
+		WHERE g.id = $1
+		RETURNING `+snapshotColumns,
+		graphID, name).StructScan(&ss)
+	if err == sql.ErrNoRows {
+		err = entity.ErrGraphNotFound
+	}
+	if terr, ok := err.(*pq.Error); ok {
+		if terr.Code == "23505" { // duplicate key violates unique constraint
+			err = entity.ErrDuplicatedSnapshotName
+		}
+	}
+	return
+}
+
+func (s *Storage) RestoreSnapshot(graphID int64, snapshotID int64) (
+	err error) {
+
+	if s.tx == nil {
+		return s.InTx(func(ts web.Storage) error {
+			return ts.RestoreSnapshot(graphID, snapshotID)
+		})
+	}
+
+	// Locking the graph prevents concurrent restores.
+	err = s.q.QueryRow(`SELECT id FROM graph WHERE id = $1 FOR UPDATE`,
+		graphID).Scan(&graphID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = entity.ErrGraphNotFound
+		}
+		return
+	}
+
+	var exists bool
+	err = s.q.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM snapshot WHERE id = $1 AND graph_id = $2)
+	`, snapshotID, graphID).Scan(&exists)
+	if err != nil {
+		return
+	}
+	if !exists {
+		return entity.ErrSnapshotNotFound
+	}
+
+	// Vertexes and edges which are in the snapshot keep their IDs and get
+	// new versions if changed, others are removed or restored.
+	queries := []struct {
+		name  string
+		query string
+	}{{
+		name: "remove edges",
+		query: `
+			DELETE FROM edge
+			WHERE graph_id = $1 AND id NOT IN (
+				SELECT r.id FROM snapshot,
+					jsonb_populate_recordset(NULL::edge, edges) r
+				WHERE snapshot.id = $2
+			)
+		`,
+	}, {
+		name: "remove vertexes",
+		query: `
+			DELETE FROM vertex
+			WHERE graph_id = $1 AND id NOT IN (
+				SELECT r.id FROM snapshot,
+					jsonb_populate_recordset(NULL::vertex, vertexes) r
+				WHERE snapshot.id = $2
+			)
+		`,
+	}, {
+		name: "restore vertexes",
+		query: `
+			INSERT INTO vertex
+			SELECT r.* FROM snapshot,
+				jsonb_populate_recordset(NULL::vertex, vertexes) r
+			WHERE snapshot.id = $2 AND snapshot.graph_id = $1
+			ON CONFLICT (id) DO UPDATE
+			SET x = EXCLUDED.x, y = EXCLUDED.y, label = EXCLUDED.label,
+				attributes = EXCLUDED.attributes,
+				version = vertex.version + 1
+			WHERE (vertex.x, vertex.y, vertex.label, vertex.attributes)
+				IS DISTINCT FROM
+				(EXCLUDED.x, EXCLUDED.y, EXCLUDED.label, EXCLUDED.attributes)
+		`,
+	}, {
+		name: "restore edges",
+		query: `
+			INSERT INTO edge
+			SELECT r.* FROM snapshot,
+				jsonb_populate_recordset(NULL::edge, edges) r
+			WHERE snapshot.id = $2 AND snapshot.graph_id = $1
+			ON CONFLICT (id) DO UPDATE
+			SET weight = EXCLUDED.weight, directed = EXCLUDED.directed,
+				label = EXCLUDED.label, attributes = EXCLUDED.attributes,
+				version = edge.version + 1
+			WHERE (edge.weight, edge.directed, edge.label, edge.attributes)
+				IS DISTINCT FROM
+				(EXCLUDED.weight, EXCLUDED.directed, EXCLUDED.label,
+					EXCLUDED.attributes)
+		`,
+	}}
+
+	for _, q := range queries {
+		_, err = s.q.Exec(q.query, graphID, snapshotID)
+		if err != nil {
+			return errors.New("failed to " + q.name + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveSnapshot(graphID int64, snapshotID int64) error {
+	res, err := s.q.Exec(`
+		DELETE FROM snapshot WHERE id = $1 AND graph_id = $2
+	`, snapshotID, graphID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrSnapshotNotFound
+	}
+
+	return nil
+}
@@ -4,20 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Boostport/migration"
 	"github.com/Boostport/migration/driver/postgres"
 	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
 	"github.com/gobuffalo/packr"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 )
 
+// queryer is implemented by both *sqlx.DB and *sqlx.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryRowx(query string, args ...interface{}) *sqlx.Row
+	Select(dest interface{}, query string, args ...interface{}) error
+}
+
 type Storage struct {
-	db  *sqlx.DB
-	uri string
+	db    *sqlx.DB
+	tx    *sqlx.Tx
+	q     queryer
+	uri   string
+	actor string
+	log   *logrus.Entry
 }
 
 func NewStorage(postgresURI string) (*Storage, error) {
@@ -34,13 +49,117 @@ func NewStorage(postgresURI string) (*Storage, error) {
 		return nil, errors.New("failed to ping DB: " + err.Error())
 	}
 
-	return &Storage{db: db, uri: postgresURI}, nil
+	return &Storage{
+		db:  db,
+		q:   db,
+		uri: postgresURI,
+		log: logrus.WithField("subsystem", "postgres_storage"),
+	}, nil
+}
+
+// InTx calls f with storage which runs all queries in one transaction.
+// The transaction is committed if f returns nil and rolled back otherwise.
+// Nested calls run in the outer transaction.
+func (s *Storage) InTx(f func(s web.Storage) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+
+	if s.actor != "" {
+		// Change log triggers take the actor from this setting.
+		_, err = tx.Exec(`SELECT set_config('graph.actor', $1, true)`,
+			s.actor)
+		if err != nil {
+			rerr := tx.Rollback()
+			if rerr != nil {
+				return errors.New("failed to rollback transaction: " +
+					rerr.Error())
+			}
+			return errors.New("failed to set actor: " + err.Error())
+		}
+	}
+
+	err = f(&Storage{db: s.db, tx: tx, q: tx, uri: s.uri, actor: s.actor,
+		log: s.log})
+	if err != nil {
+		rerr := tx.Rollback()
+		if rerr != nil {
+			return errors.New("failed to rollback transaction: " +
+				rerr.Error())
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return nil
+}
+
+// InReadTx calls f with storage which runs all queries in one read-only
+// repeatable read transaction, so they see the same snapshot of data.
+func (s *Storage) InReadTx(f func(s web.Storage) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	tx, err := s.db.BeginTxx(context.TODO(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+
+	err = f(&Storage{db: s.db, tx: tx, q: tx, uri: s.uri, actor: s.actor,
+		log: s.log})
+	if err != nil {
+		rerr := tx.Rollback()
+		if rerr != nil {
+			return errors.New("failed to rollback transaction: " +
+				rerr.Error())
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return nil
+}
+
+// WithActor returns storage which records actor as the author of changes
+// in the change log.
+func (s *Storage) WithActor(actor string) web.Storage {
+	s2 := *s
+	s2.actor = actor
+	return &s2
+}
+
+// needsTx reports whether mutation should be run in the separate
+// transaction to record the actor.
+func (s *Storage) needsTx() bool {
+	return s.actor != "" && s.tx == nil
 }
 
 //go:generate packr
 
 const migrationsPath = "./migrations"
 
+// edgeColumns selects edge rows into entity.Edge, which keeps undirected
+// flag rather than directed one.
+const edgeColumns = `id, graph_id, "from", "to", weight,
+	NOT directed AS undirected, label, attributes, version`
+
 func (s *Storage) Migrate() error {
 	packrSource := &migration.PackrMigrationSource{
 		Box: packr.NewBox(migrationsPath),
@@ -60,7 +179,7 @@ func (s *Storage) Migrate() error {
 }
 
 func (s *Storage) Graph(graphID int64) (g entity.Graph, err error) {
-	err = s.db.QueryRowx(`SELECT * FROM graph WHERE id = $1`, graphID).
+	err = s.q.QueryRowx(`SELECT * FROM graph WHERE id = $1`, graphID).
 		StructScan(&g)
 	if err == sql.ErrNoRows {
 		err = entity.ErrGraphNotFound
@@ -69,14 +188,22 @@ func (s *Storage) Graph(graphID int64) (g entity.Graph, err error) {
 }
 
 func (s *Storage) Graphs() (gs []entity.Graph, err error) {
-	err = s.db.Select(&gs, `SELECT * FROM graph ORDER BY name`)
+	err = s.q.Select(&gs, `SELECT * FROM graph ORDER BY name`)
 	return
 }
 
 func (s *Storage) AddGraph(g entity.Graph) (id int64, err error) {
-	err = s.db.QueryRow(`
-		INSERT INTO graph (name) VALUES ($1) RETURNING id
-	`, g.Name).Scan(&id)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddGraph(g)
+			return
+		})
+		return
+	}
+
+	err = s.q.QueryRow(`
+		INSERT INTO graph (name, directed) VALUES ($1, $2) RETURNING id
+	`, g.Name, g.Directed).Scan(&id)
 	if terr, ok := err.(*pq.Error); ok {
 		if terr.Code == "23505" { // duplicate key violates unique constraint
 			err = entity.ErrDuplicatedGraphName
@@ -85,13 +212,82 @@ func (s *Storage) AddGraph(g entity.Graph) (id int64, err error) {
 	return
 }
 
+// CloneGraph copies the graph with its vertexes and edges into the new
+// graph with the given name. Copied vertexes and edges get new IDs.
+func (s *Storage) CloneGraph(graphID int64, name string) (id int64,
+	err error) {
+
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.CloneGraph(graphID, name)
+			return
+		})
+		return
+	}
+
+	err = s.q.QueryRow(`
+		INSERT INTO graph (name, directed)
+		SELECT $2, directed FROM graph WHERE id = $1
+		RETURNING id
+	`, graphID, name).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = entity.ErrGraphNotFound
+		}
+		if terr, ok := err.(*pq.Error); ok {
+			if terr.Code == "23505" { // duplicate key violates unique constraint
+				err = entity.ErrDuplicatedGraphName
+			}
+		}
+		return
+	}
+
+	// New vertex IDs are taken from the sequence beforehand to remap edge
+	// endpoints in the same statement.
+	_, err = s.q.Exec(`
+		WITH ids AS (
+			SELECT id AS old_id,
+				nextval(pg_get_serial_sequence('vertex', 'id')) AS new_id
+			FROM vertex
+			WHERE graph_id = $1
+		), vertexes AS (
+			INSERT INTO vertex (id, graph_id, x, y, label, attributes)
+			SELECT ids.new_id, $2, v.x, v.y, v.label, v.attributes
+			FROM vertex v
+			JOIN ids ON ids.old_id = v.id
+		)
+		INSERT INTO edge (graph_id, "from", "to", weight, directed, label,
+			attributes)
+		SELECT $2, f.new_id, t.new_id, e.weight, e.directed, e.label,
+			e.attributes
+		FROM edge e
+		JOIN ids f ON f.old_id = e."from"
+		JOIN ids t ON t.old_id = e."to"
+		WHERE e.graph_id = $1
+	`, graphID, id)
+	if err != nil {
+		return 0, errors.New("failed to copy vertexes and edges: " +
+			err.Error())
+	}
+
+	return id, nil
+}
+
 func (s *Storage) RemoveGraph(graphID int64) (err error) {
-	_, err = s.db.Exec(`DELETE FROM graph WHERE id = $1`, graphID)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			err = ts.RemoveGraph(graphID)
+			return
+		})
+		return
+	}
+
+	_, err = s.q.Exec(`DELETE FROM graph WHERE id = $1`, graphID)
 	return
 }
 
 func (s *Storage) Vertex(vertexID int64) (v entity.Vertex, err error) {
-	err = s.db.QueryRowx(`SELECT * FROM vertex WHERE id = $1`,
+	err = s.q.QueryRowx(`SELECT * FROM vertex WHERE id = $1`,
 		vertexID).StructScan(&v)
 	if err == sql.ErrNoRows {
 		err = entity.ErrVertexNotFound
@@ -100,44 +296,81 @@ func (s *Storage) Vertex(vertexID int64) (v entity.Vertex, err error) {
 }
 
 func (s *Storage) Vertexes(graphID int64) (vs []entity.Vertex, err error) {
-	err = s.db.Select(&vs, `SELECT * FROM vertex WHERE graph_id = $1`,
+	err = s.q.Select(&vs, `SELECT * FROM vertex WHERE graph_id = $1`,
 		graphID)
 	return
 }
 
 func (s *Storage) AddVertex(v entity.Vertex) (id int64, err error) {
-	err = s.db.QueryRow(`
-		INSERT INTO vertex (graph_id, x, y)
-		VALUES ($1, $2, $3)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddVertex(v)
+			return
+		})
+		return
+	}
+
+	err = s.q.QueryRow(`
+		INSERT INTO vertex (graph_id, x, y, label, attributes)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
-	`, v.GraphID, v.X, v.Y).Scan(&id)
+	`, v.GraphID, v.X, v.Y, v.Label, v.Attributes).Scan(&id)
+	if terr, ok := err.(*pq.Error); ok {
+		if terr.Code == "23503" { // insert violates foreign key constraint
+			err = entity.ErrGraphNotFound
+		}
+	}
 	return
 }
 
-func (s *Storage) SetVertex(v entity.Vertex) error {
-	res, err := s.db.Exec(`
-		UPDATE vertex SET x = $1, y = $2 WHERE id = $3
-	`, v.X, v.Y, v.ID)
-	if err != nil {
-		return err
+func (s *Storage) SetVertex(v entity.Vertex) (version int64, err error) {
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			version, err = ts.SetVertex(v)
+			return
+		})
+		return
 	}
-	updates, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if updates == 0 {
-		return entity.ErrVertexNotFound
+
+	err = s.q.QueryRow(`
+		UPDATE vertex
+		SET x = $1, y = $2, label = $3, attributes = $4,
+			version = version + 1
+		WHERE id = $5 AND ($6 = 0 OR version = $6)
+		RETURNING version
+	`, v.X, v.Y, v.Label, v.Attributes, v.ID, v.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = s.q.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM vertex WHERE id = $1)
+		`, v.ID).Scan(&exists)
+		if err != nil {
+			return
+		}
+		if exists {
+			err = entity.ErrVersionConflict
+		} else {
+			err = entity.ErrVertexNotFound
+		}
 	}
-	return nil
+	return
 }
 
 func (s *Storage) RemoveVertex(vertexID int64) (err error) {
-	_, err = s.db.Exec(`DELETE FROM vertex WHERE id = $1`, vertexID)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			err = ts.RemoveVertex(vertexID)
+			return
+		})
+		return
+	}
+
+	_, err = s.q.Exec(`DELETE FROM vertex WHERE id = $1`, vertexID)
 	return
 }
 
 func (s *Storage) Edge(edgeID int64) (e entity.Edge, err error) {
-	err = s.db.QueryRowx(`SELECT * FROM edge WHERE id = $1`,
+	err = s.q.QueryRowx(`SELECT `+edgeColumns+` FROM edge WHERE id = $1`,
 		edgeID).StructScan(&e)
 	if err == sql.ErrNoRows {
 		err = entity.ErrEdgeNotFound
@@ -146,38 +379,147 @@ func (s *Storage) Edge(edgeID int64) (e entity.Edge, err error) {
 }
 
 func (s *Storage) Edges(graphID int64) (es []entity.Edge, err error) {
-	err = s.db.Select(&es, `SELECT * FROM edge WHERE graph_id = $1`,
+	err = s.q.Select(&es, `SELECT `+edgeColumns+` FROM edge WHERE graph_id = $1`,
 		graphID)
 	return
 }
 
 func (s *Storage) AddEdge(e entity.Edge) (id int64, err error) {
-	err = s.db.QueryRow(`
-		INSERT INTO edge (graph_id, "from", "to", weight)
-		VALUES ($1, $2, $3, $4)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddEdge(e)
+			return
+		})
+		return
+	}
+
+	err = s.q.QueryRow(`
+		INSERT INTO edge (graph_id, "from", "to", weight, directed, label,
+			attributes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id
-	`, e.GraphID, e.From, e.To, e.Weight).Scan(&id)
+	`, e.GraphID, e.From, e.To, e.Weight, !e.Undirected, e.Label,
+		e.Attributes).Scan(&id)
+	if terr, ok := err.(*pq.Error); ok {
+		if terr.Code == "23503" { // insert violates foreign key constraint
+			if terr.Constraint == "edge_graph_id_fkey" {
+				err = entity.ErrGraphNotFound
+			} else {
+				err = entity.ErrVertexNotFound
+			}
+		}
+	}
 	return
 }
 
-func (s *Storage) SetEdge(e entity.Edge) error {
-	res, err := s.db.Exec(`
-		UPDATE edge SET weight = $1 WHERE id = $2
-	`, e.Weight, e.ID)
-	if err != nil {
-		return err
-	}
-	updates, err := res.RowsAffected()
-	if err != nil {
-		return err
+func (s *Storage) SetEdge(e entity.Edge) (version int64, err error) {
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			version, err = ts.SetEdge(e)
+			return
+		})
+		return
 	}
-	if updates == 0 {
-		return entity.ErrEdgeNotFound
+
+	err = s.q.QueryRow(`
+		UPDATE edge
+		SET weight = $1, directed = $2, label = $3, attributes = $4,
+			version = version + 1
+		WHERE id = $5 AND ($6 = 0 OR version = $6)
+		RETURNING version
+	`, e.Weight, !e.Undirected, e.Label, e.Attributes, e.ID,
+		e.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = s.q.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM edge WHERE id = $1)
+		`, e.ID).Scan(&exists)
+		if err != nil {
+			return
+		}
+		if exists {
+			err = entity.ErrVersionConflict
+		} else {
+			err = entity.ErrEdgeNotFound
+		}
 	}
-	return nil
+	return
 }
 
 func (s *Storage) RemoveEdge(edgeID int64) (err error) {
-	_, err = s.db.Exec(`DELETE FROM edge WHERE id = $1`, edgeID)
+	if s.needsTx() {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			err = ts.RemoveEdge(edgeID)
+			return
+		})
+		return
+	}
+
+	_, err = s.q.Exec(`DELETE FROM edge WHERE id = $1`, edgeID)
 	return
 }
+
+// changeRow is the change log record as it is stored. json.RawMessage can
+// not be scanned from NULL, so before and after are scanned as bytes.
+type changeRow struct {
+	entity.Change
+	Before []byte `db:"before"`
+	After  []byte `db:"after"`
+}
+
+// selectChanges returns change log records selected by query.
+func (s *Storage) selectChanges(query string, args ...interface{}) (
+	[]entity.Change, error) {
+
+	var rows []changeRow
+	err := s.q.Select(&rows, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs []entity.Change
+	for _, r := range rows {
+		c := r.Change
+		c.Before, c.After = r.Before, r.After
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+func (s *Storage) Changes(graphID int64, f entity.ChangeFilter) (
+	[]entity.Change, error) {
+
+	query := `SELECT * FROM change WHERE graph_id = $1`
+	args := []interface{}{graphID}
+
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+
+	if f.Entity != "" {
+		where("entity = $%d", f.Entity)
+	}
+	if f.EntityID != 0 {
+		where("entity_id = $%d", f.EntityID)
+	}
+	if !f.Since.IsZero() {
+		where("created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		where("created_at < $%d", f.Until)
+	}
+	if f.BeforeID != 0 {
+		where("id < $%d", f.BeforeID)
+	}
+
+	query += ` ORDER BY id DESC`
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	return s.selectChanges(query, args...)
+}
@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dimuls/graph/storagetest"
+	"github.com/dimuls/graph/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// testURIEnv is the environment variable with URI of Postgres server for
+// tests, for example postgres://postgres@localhost/postgres?sslmode=disable.
+// Without it tests start their own server if initdb and pg_ctl are
+// installed. Every test gets its own database.
+//
+// Tests are skipped only if neither is available. If the variable is set
+// or Postgres is installed, but the server can not be used, all tests of
+// the package fail.
+const testURIEnv = "POSTGRES_TEST_URI"
+
+// errNoPostgres is returned by startPostgres if Postgres is not installed.
+var errNoPostgres = errors.New("initdb and pg_ctl are not found")
+
+var (
+	testURI       string
+	testDatabases int64
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	testURI = os.Getenv(testURIEnv)
+
+	if testURI != "" {
+		s, err := NewStorage(testURI)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, testURIEnv+" is set, but postgres is "+
+				"not available: "+err.Error())
+			return 1
+		}
+		s.db.Close()
+		return m.Run()
+	}
+
+	uri, stop, err := startPostgres()
+	if err == errNoPostgres {
+		fmt.Fprintln(os.Stderr, "postgres is not installed, tests which "+
+			"need it are skipped, set "+testURIEnv+" to run them")
+		return m.Run()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start postgres, set "+
+			testURIEnv+" to use running server: "+err.Error())
+		return 1
+	}
+	defer stop()
+
+	testURI = uri
+
+	return m.Run()
+}
+
+// postgresBin returns the path of Postgres server binary.
+func postgresBin(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err == nil {
+		return path, nil
+	}
+
+	// Debian and Ubuntu do not put server binaries to PATH.
+	paths, _ := filepath.Glob("/usr/lib/postgresql/*/bin/" + name)
+	if len(paths) > 0 {
+		return paths[len(paths)-1], nil
+	}
+
+	return "", errNoPostgres
+}
+
+// startPostgres starts Postgres server in the temporary directory and
+// returns its URI and the function which stops it. initdb refuses to run
+// as root, so tests run by root need POSTGRES_TEST_URI.
+func startPostgres() (uri string, stop func(), err error) {
+	initdb, err := postgresBin("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+
+	pgCtl, err := postgresBin("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if os.Geteuid() == 0 {
+		return "", nil, errors.New("initdb can not be run by root")
+	}
+
+	dir, err := os.MkdirTemp("", "graph-postgres-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres",
+		"-A", "trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	out, err = exec.Command(pgCtl, "-D", data, "-l",
+		filepath.Join(dir, "postgres.log"), "-w", "-o",
+		fmt.Sprintf("-h 127.0.0.1 -p %d -k %s -F", port, dir),
+		"start").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	stop = func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	uri = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres"+
+		"?sslmode=disable", port)
+
+	return uri, stop, nil
+}
+
+// newTestStorage returns the migrated storage in the new database which
+// is dropped after the test.
+func newTestStorage(t *testing.T) web.Storage {
+	if testURI == "" {
+		t.Skip("postgres is not installed, set " + testURIEnv)
+	}
+
+	admin, err := sqlx.Open("postgres", testURI)
+	if err != nil {
+		t.Fatalf("failed to open DB: %v", err)
+	}
+
+	name := fmt.Sprintf("graph_test_%d_%d", os.Getpid(),
+		atomic.AddInt64(&testDatabases, 1))
+
+	_, err = admin.Exec(`CREATE DATABASE ` + name)
+	if err != nil {
+		admin.Close()
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_, err := admin.Exec(`DROP DATABASE ` + name)
+		if err != nil {
+			t.Logf("failed to drop database %s: %v", name, err)
+		}
+		admin.Close()
+	})
+
+	u, err := url.Parse(testURI)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", testURIEnv, err)
+	}
+	u.Path = "/" + name
+
+	s, err := NewStorage(u.String())
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	// Connections are closed before the database is dropped.
+	t.Cleanup(func() {
+		s.db.Close()
+	})
+
+	err = s.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	return s
+}
+
+func TestStorage_conformance(t *testing.T) {
+	storagetest.Run(t, newTestStorage)
+}
+
+func TestStorage_Notify(t *testing.T) {
+	s := newTestStorage(t).(*Storage)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	ns := make(chan web.Notification, 10)
+	go func() {
+		err := s.Listen(stop, func(n web.Notification) {
+			ns <- n
+		})
+		if err != nil {
+			t.Errorf("Listen() error = %v", err)
+		}
+	}()
+
+	small := web.Notification{
+		Origin:  "a",
+		GraphID: 1,
+		Message: json.RawMessage(`{"type":"vertex-removed"}`),
+	}
+
+	large := small
+	large.Message = json.RawMessage(`"` +
+		strings.Repeat("x", maxNotifyPayload) + `"`)
+
+	// Notifications sent before LISTEN is executed are lost, so the first
+	// one is repeated until it is received.
+	timeout := time.After(10 * time.Second)
+	for received := false; !received; {
+		err := s.Notify(small)
+		if err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		select {
+		case n := <-ns:
+			if n.Origin != small.Origin || n.GraphID != small.GraphID ||
+				string(n.Message) != string(small.Message) {
+				t.Fatalf("Listen() got = %+v, want %+v", n, small)
+			}
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("Listen() got no notifications")
+		}
+	}
+
+	// Drain repeated notifications.
+	time.Sleep(100 * time.Millisecond)
+	for len(ns) > 0 {
+		<-ns
+	}
+
+	err := s.Notify(large)
+	if err != nil {
+		t.Fatalf("Notify() of large message error = %v", err)
+	}
+
+	select {
+	case n := <-ns:
+		if n.GraphID != large.GraphID || n.Message != nil {
+			t.Errorf("Listen() got = %+v, want notification of graph %d "+
+				"without message", n, large.GraphID)
+		}
+	case <-timeout:
+		t.Fatal("Listen() got no notification of large message")
+	}
+}
+
+func TestStorage_NotifyInTx(t *testing.T) {
+	s := newTestStorage(t).(*Storage)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	ns := make(chan web.Notification, 10)
+	go func() {
+		err := s.Listen(stop, func(n web.Notification) {
+			ns <- n
+		})
+		if err != nil {
+			t.Errorf("Listen() error = %v", err)
+		}
+	}()
+
+	// Notifications sent before LISTEN is executed are lost, so the first
+	// one is repeated until it is received.
+	timeout := time.After(10 * time.Second)
+	for received := false; !received; {
+		err := s.Notify(web.Notification{Origin: "ready"})
+		if err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		select {
+		case <-ns:
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("Listen() got no notifications")
+		}
+	}
+
+	// Drain repeated notifications.
+	time.Sleep(100 * time.Millisecond)
+	for len(ns) > 0 {
+		<-ns
+	}
+
+	errRollback := errors.New("rollback")
+
+	err := s.InTx(func(ts web.Storage) error {
+		err := ts.(*Storage).Notify(web.Notification{Origin: "rolled-back",
+			GraphID: 1})
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("InTx() error = %v, want %v", err, errRollback)
+	}
+
+	err = s.InTx(func(ts web.Storage) error {
+		return ts.(*Storage).Notify(web.Notification{Origin: "committed",
+			GraphID: 1})
+	})
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	select {
+	case n := <-ns:
+		if n.Origin != "committed" {
+			t.Errorf("Listen() got = %+v, want committed notification", n)
+		}
+	case <-timeout:
+		t.Fatal("Listen() got no committed notification")
+	}
+}
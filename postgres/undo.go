@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+)
+
+// Queries restoring vertexes and edges from their JSON representation
+// stored in the change log.
+var (
+	deleteQueries = map[string]string{
+		entity.EntityVertex: `DELETE FROM vertex WHERE id = $1`,
+		entity.EntityEdge:   `DELETE FROM edge WHERE id = $1`,
+	}
+
+	updateQueries = map[string]string{
+		entity.EntityVertex: `
+			UPDATE vertex
+			SET x = r.x, y = r.y, label = r.label,
+				attributes = r.attributes, version = vertex.version + 1
+			FROM jsonb_populate_record(NULL::vertex, $1::jsonb) r
+			WHERE vertex.id = r.id
+		`,
+		entity.EntityEdge: `
+			UPDATE edge
+			SET weight = r.weight, directed = r.directed, label = r.label,
+				attributes = r.attributes, version = edge.version + 1
+			FROM jsonb_populate_record(NULL::edge, $1::jsonb) r
+			WHERE edge.id = r.id
+		`,
+	}
+
+	insertQueries = map[string]string{
+		entity.EntityVertex: `
+			INSERT INTO vertex
+			SELECT * FROM jsonb_populate_record(NULL::vertex, $1::jsonb)
+		`,
+		entity.EntityEdge: `
+			INSERT INTO edge
+			SELECT * FROM jsonb_populate_record(NULL::edge, $1::jsonb)
+		`,
+	}
+)
+
+func (s *Storage) Undo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, true)
+}
+
+func (s *Storage) Redo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, false)
+}
+
+type stateKey struct {
+	entity string
+	id     int64
+}
+
+// state is the state of vertex or edge to restore. Nil data means that
+// the entity should not exist.
+type state struct {
+	stateKey
+	data json.RawMessage
+}
+
+// step undoes or redoes the history step of the graph.
+func (s *Storage) step(graphID int64, undo bool) (
+	cs []entity.Change, err error) {
+
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			cs, err = ts.(*Storage).step(graphID, undo)
+			return
+		})
+		return
+	}
+
+	// Locking the graph serializes concurrent undo and redo.
+	err = s.q.QueryRow(`SELECT id FROM graph WHERE id = $1 FOR UPDATE`,
+		graphID).Scan(&graphID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = entity.ErrGraphNotFound
+		}
+		return
+	}
+
+	var (
+		stepID int64
+		txID   int64
+		query  string
+		mode   string
+	)
+
+	if undo {
+		query = `
+			SELECT id, tx_id FROM history_step
+			WHERE graph_id = $1 AND NOT undone
+			ORDER BY id DESC LIMIT 1
+		`
+		mode = "undo"
+	} else {
+		query = `
+			SELECT id, tx_id FROM history_step
+			WHERE graph_id = $1 AND undone
+			ORDER BY id LIMIT 1
+		`
+		mode = "redo"
+	}
+
+	err = s.q.QueryRow(query, graphID).Scan(&stepID, &txID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if undo {
+				err = entity.ErrNothingToUndo
+			} else {
+				err = entity.ErrNothingToRedo
+			}
+		}
+		return
+	}
+
+	// Changes made with graph.history set do not make new steps.
+	_, err = s.q.Exec(`SELECT set_config('graph.history', $1, true)`, mode)
+	if err != nil {
+		return nil, errors.New("failed to set history mode: " + err.Error())
+	}
+
+	changes, err := s.selectChanges(`
+		SELECT * FROM change
+		WHERE graph_id = $1 AND tx_id = $2 AND entity <> $3
+		ORDER BY id
+	`, graphID, txID, entity.EntityGraph)
+	if err != nil {
+		return nil, errors.New("failed to get step changes: " + err.Error())
+	}
+
+	// Undo restores the state before the first change of every entity in
+	// the step and redo restores the state after the last one.
+	var (
+		states []*state
+		index  = map[stateKey]*state{}
+	)
+	for _, c := range changes {
+		key := stateKey{entity: c.Entity, id: c.EntityID}
+		st, exists := index[key]
+		if !exists {
+			st = &state{stateKey: key, data: c.Before}
+			index[key] = st
+			states = append(states, st)
+		}
+		if !undo {
+			st.data = c.After
+		}
+	}
+
+	// Edges are deleted before vertexes and restored after them so that
+	// foreign keys hold.
+	apply := func(entityName string, deleted bool) error {
+		for _, st := range states {
+			if st.entity != entityName || (st.data == nil) != deleted {
+				continue
+			}
+			if deleted {
+				_, err := s.q.Exec(deleteQueries[st.entity], st.id)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			res, err := s.q.Exec(updateQueries[st.entity], string(st.data))
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				_, err = s.q.Exec(insertQueries[st.entity], string(st.data))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, a := range []struct {
+		entity  string
+		deleted bool
+	}{
+		{entity.EntityEdge, true},
+		{entity.EntityVertex, true},
+		{entity.EntityVertex, false},
+		{entity.EntityEdge, false},
+	} {
+		err = apply(a.entity, a.deleted)
+		if err != nil {
+			return nil, errors.New("failed to apply " + mode + ": " +
+				err.Error())
+		}
+	}
+
+	_, err = s.q.Exec(`UPDATE history_step SET undone = $1 WHERE id = $2`,
+		undo, stepID)
+	if err != nil {
+		return nil, errors.New("failed to update history step: " +
+			err.Error())
+	}
+
+	cs, err = s.selectChanges(`
+		SELECT * FROM change
+		WHERE graph_id = $1 AND tx_id = txid_current()
+		ORDER BY id
+	`, graphID)
+	if err != nil {
+		return nil, errors.New("failed to get changes: " + err.Error())
+	}
+
+	return cs, nil
+}
@@ -0,0 +1,7 @@
+DROP TABLE snapshot;
+DROP TABLE history_step;
+DROP TABLE change;
+DROP TABLE tx;
+DROP TABLE edge;
+DROP TABLE vertex;
+DROP TABLE graph;
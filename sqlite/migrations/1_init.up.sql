@@ -0,0 +1,75 @@
+CREATE TABLE graph (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    directed BOOLEAN NOT NULL DEFAULT TRUE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE vertex (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    graph_id INTEGER NOT NULL REFERENCES graph (id) ON DELETE CASCADE,
+    x REAL NOT NULL,
+    y REAL NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    attributes TEXT NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX vertex_graph_id_idx ON vertex (graph_id);
+
+CREATE TABLE edge (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    graph_id INTEGER NOT NULL REFERENCES graph (id) ON DELETE CASCADE,
+    "from" INTEGER NOT NULL REFERENCES vertex (id) ON DELETE CASCADE,
+    "to" INTEGER NOT NULL REFERENCES vertex (id) ON DELETE CASCADE,
+    weight REAL NOT NULL,
+    directed BOOLEAN NOT NULL DEFAULT TRUE,
+    label TEXT NOT NULL DEFAULT '',
+    attributes TEXT NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX edge_graph_id_idx ON edge (graph_id);
+CREATE INDEX edge_from_idx ON edge ("from");
+CREATE INDEX edge_to_idx ON edge ("to");
+
+-- SQLite has no transaction IDs, so they are taken from this table.
+CREATE TABLE tx (
+    id INTEGER PRIMARY KEY AUTOINCREMENT
+);
+
+CREATE TABLE change (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    graph_id INTEGER NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    operation TEXT NOT NULL,
+    before TEXT,
+    after TEXT,
+    actor TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    tx_id INTEGER NOT NULL
+);
+
+CREATE INDEX change_graph_id_id_idx ON change (graph_id, id);
+CREATE INDEX change_graph_id_tx_id_idx ON change (graph_id, tx_id);
+
+CREATE TABLE history_step (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    graph_id INTEGER NOT NULL,
+    tx_id INTEGER NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (graph_id, tx_id)
+);
+
+CREATE TABLE snapshot (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    graph_id INTEGER NOT NULL REFERENCES graph (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    vertexes TEXT NOT NULL,
+    edges TEXT NOT NULL,
+    vertex_count INTEGER NOT NULL,
+    edge_count INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (graph_id, name)
+);
@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+)
+
+func (s *Storage) Snapshots(graphID int64) (ss []entity.Snapshot, err error) {
+	err = s.q.Select(&ss, `
+		SELECT id, graph_id, name, vertex_count, edge_count, created_at
+		FROM snapshot
+		WHERE graph_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, graphID)
+	return
+}
+
+func (s *Storage) AddSnapshot(graphID int64, name string) (
+	ss entity.Snapshot, err error) {
+
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			ss, err = ts.AddSnapshot(graphID, name)
+			return
+		})
+		return
+	}
+
+	_, err = s.Graph(graphID)
+	if err != nil {
+		return
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		return ss, errors.New("failed to get vertexes: " + err.Error())
+	}
+
+	es, err := s.Edges(graphID)
+	if err != nil {
+		return ss, errors.New("failed to get edges: " + err.Error())
+	}
+
+	// Empty graph is saved as empty arrays rather than nulls.
+	if vs == nil {
+		vs = []entity.Vertex{}
+	}
+	if es == nil {
+		es = []entity.Edge{}
+	}
+
+	vertexesData, err := json.Marshal(vs)
+	if err != nil {
+		return
+	}
+
+	edgesData, err := json.Marshal(es)
+	if err != nil {
+		return
+	}
+
+	ss = entity.Snapshot{
+		GraphID:     graphID,
+		Name:        name,
+		VertexCount: len(vs),
+		EdgeCount:   len(es),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	res, err := s.q.Exec(`
+		INSERT INTO snapshot (graph_id, name, vertexes, edges, vertex_count,
+			edge_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, ss.GraphID, ss.Name, vertexesData, edgesData, ss.VertexCount,
+		ss.EdgeCount, ss.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			err = entity.ErrDuplicatedSnapshotName
+		}
+		return entity.Snapshot{}, err
+	}
+
+	ss.ID, err = res.LastInsertId()
+	if err != nil {
+		return entity.Snapshot{}, err
+	}
+
+	return ss, nil
+}
+
+func (s *Storage) RestoreSnapshot(graphID int64, snapshotID int64) (
+	err error) {
+
+	if s.tx == nil {
+		return s.InTx(func(ts web.Storage) error {
+			return ts.RestoreSnapshot(graphID, snapshotID)
+		})
+	}
+
+	_, err = s.Graph(graphID)
+	if err != nil {
+		return
+	}
+
+	var vertexesData, edgesData []byte
+	err = s.q.QueryRow(`
+		SELECT vertexes, edges FROM snapshot WHERE id = ? AND graph_id = ?
+	`, snapshotID, graphID).Scan(&vertexesData, &edgesData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = entity.ErrSnapshotNotFound
+		}
+		return
+	}
+
+	var (
+		snapVertexes []entity.Vertex
+		snapEdges    []entity.Edge
+	)
+
+	err = json.Unmarshal(vertexesData, &snapVertexes)
+	if err != nil {
+		return errors.New("failed to decode vertexes: " + err.Error())
+	}
+
+	err = json.Unmarshal(edgesData, &snapEdges)
+	if err != nil {
+		return errors.New("failed to decode edges: " + err.Error())
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		return errors.New("failed to get vertexes: " + err.Error())
+	}
+
+	es, err := s.Edges(graphID)
+	if err != nil {
+		return errors.New("failed to get edges: " + err.Error())
+	}
+
+	vertexes := make(map[int64]entity.Vertex, len(vs))
+	for _, v := range vs {
+		vertexes[v.ID] = v
+	}
+
+	edges := make(map[int64]entity.Edge, len(es))
+	for _, e := range es {
+		edges[e.ID] = e
+	}
+
+	vertexIDs := make(map[int64]bool, len(snapVertexes))
+	for _, v := range snapVertexes {
+		vertexIDs[v.ID] = true
+	}
+
+	edgeIDs := make(map[int64]bool, len(snapEdges))
+	for _, e := range snapEdges {
+		edgeIDs[e.ID] = true
+	}
+
+	// Vertexes and edges which are in the snapshot keep their IDs and get
+	// new versions if changed, others are removed or restored.
+	for _, e := range es {
+		if !edgeIDs[e.ID] {
+			err = s.removeEdge(e)
+			if err != nil {
+				return errors.New("failed to remove edge: " + err.Error())
+			}
+		}
+	}
+
+	for _, v := range vs {
+		if !vertexIDs[v.ID] {
+			err = s.removeVertex(v)
+			if err != nil {
+				return errors.New("failed to remove vertex: " + err.Error())
+			}
+		}
+	}
+
+	for _, v := range snapVertexes {
+		old, exists := vertexes[v.ID]
+		if !exists {
+			_, err = s.insertVertex(v)
+		} else if old.X != v.X || old.Y != v.Y || old.Label != v.Label ||
+			!reflect.DeepEqual(old.Attributes, v.Attributes) {
+
+			_, err = s.updateVertex(old, v)
+		}
+		if err != nil {
+			return errors.New("failed to restore vertex: " + err.Error())
+		}
+	}
+
+	for _, e := range snapEdges {
+		old, exists := edges[e.ID]
+		if !exists {
+			_, err = s.insertEdge(e)
+		} else if old.Weight != e.Weight || old.Undirected != e.Undirected ||
+			old.Label != e.Label ||
+			!reflect.DeepEqual(old.Attributes, e.Attributes) {
+
+			_, err = s.updateEdge(old, e)
+		}
+		if err != nil {
+			return errors.New("failed to restore edge: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveSnapshot(graphID int64, snapshotID int64) error {
+	res, err := s.q.Exec(`
+		DELETE FROM snapshot WHERE id = ? AND graph_id = ?
+	`, snapshotID, graphID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return entity.ErrSnapshotNotFound
+	}
+
+	return nil
+}
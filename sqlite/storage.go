@@ -0,0 +1,810 @@
+// Package sqlite implements web.Storage which keeps graphs in the single
+// SQLite database file. SQLite has no triggers which could read
+// transaction settings, so unlike postgres storage the change log, undo
+// steps and cascade deletes are maintained by the storage itself.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Boostport/migration"
+	"github.com/Boostport/migration/driver/sqlite"
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+	"github.com/gobuffalo/packr"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// queryer is implemented by both *sqlx.DB and *sqlx.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryRowx(query string, args ...interface{}) *sqlx.Row
+	Select(dest interface{}, query string, args ...interface{}) error
+}
+
+// txInfo is shared by storages of one transaction.
+type txInfo struct {
+	// id is taken from tx table on the first change.
+	id        int64
+	startedAt time.Time
+
+	// history is set by undo and redo which manage undo steps themselves.
+	history bool
+}
+
+type Storage struct {
+	db    *sqlx.DB
+	rdb   *sqlx.DB
+	tx    *sqlx.Tx
+	q     queryer
+	info  *txInfo
+	dsn   string
+	actor string
+}
+
+// dsnParams enable foreign keys and make transactions take the write lock
+// at once, so concurrent transactions wait for each other instead of
+// failing on upgrade of the read lock.
+const dsnParams = "?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate" +
+	"&_journal_mode=WAL"
+
+// readDSNParams are dsnParams of connections for read transactions. They
+// do not take the write lock and can not change data, WAL lets them read
+// the snapshot while the writer commits.
+const readDSNParams = "?_foreign_keys=on&_busy_timeout=5000" +
+	"&_txlock=deferred&_query_only=true"
+
+// NewStorage opens the database file at path creating it if it does not
+// exist.
+func NewStorage(path string) (*Storage, error) {
+	dsn := "file:" + path + dsnParams
+
+	db, err := sqlx.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, errors.New("failed to open DB: " + err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+
+	err = db.PingContext(ctx)
+	cancel()
+	if err != nil {
+		db.Close()
+		return nil, errors.New("failed to ping DB: " + err.Error())
+	}
+
+	rdb, err := sqlx.Open("sqlite3", "file:"+path+readDSNParams)
+	if err != nil {
+		db.Close()
+		return nil, errors.New("failed to open DB: " + err.Error())
+	}
+
+	return &Storage{db: db, rdb: rdb, q: db, dsn: dsn}, nil
+}
+
+// InTx calls f with storage which runs all queries in one transaction.
+// The transaction is committed if f returns nil and rolled back otherwise.
+// Nested calls run in the outer transaction.
+func (s *Storage) InTx(f func(s web.Storage) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+
+	err = f(&Storage{
+		db:    s.db,
+		rdb:   s.rdb,
+		tx:    tx,
+		q:     tx,
+		info:  &txInfo{startedAt: time.Now().UTC()},
+		dsn:   s.dsn,
+		actor: s.actor,
+	})
+	if err != nil {
+		rerr := tx.Rollback()
+		if rerr != nil {
+			return errors.New("failed to rollback transaction: " +
+				rerr.Error())
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return nil
+}
+
+// InReadTx calls f with storage which runs all queries in one deferred
+// transaction of the read-only connection. It sees the snapshot of data
+// taken by its first query and does not block writers.
+func (s *Storage) InReadTx(f func(s web.Storage) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	tx, err := s.rdb.Beginx()
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+
+	err = f(&Storage{
+		db:    s.db,
+		rdb:   s.rdb,
+		tx:    tx,
+		q:     tx,
+		info:  &txInfo{startedAt: time.Now().UTC()},
+		dsn:   s.dsn,
+		actor: s.actor,
+	})
+	if err != nil {
+		rerr := tx.Rollback()
+		if rerr != nil {
+			return errors.New("failed to rollback transaction: " +
+				rerr.Error())
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("failed to commit transaction: " + err.Error())
+	}
+
+	return nil
+}
+
+// WithActor returns storage which records actor as the author of changes
+// in the change log.
+func (s *Storage) WithActor(actor string) web.Storage {
+	s2 := *s
+	s2.actor = actor
+	return &s2
+}
+
+//go:generate packr
+
+const migrationsPath = "./migrations"
+
+// edgeColumns selects edge rows into entity.Edge, which keeps undirected
+// flag rather than directed one.
+const edgeColumns = `id, graph_id, "from", "to", weight,
+	NOT directed AS undirected, label, attributes, version`
+
+func (s *Storage) Migrate() error {
+	packrSource := &migration.PackrMigrationSource{
+		Box: packr.NewBox(migrationsPath),
+	}
+
+	d, err := sqlite.New(s.dsn)
+	if err != nil {
+		return errors.New("failed to create migration driver: " + err.Error())
+	}
+	defer d.Close()
+
+	_, err = migration.Migrate(d, packrSource, migration.Up, 0)
+	if err != nil {
+		return errors.New("failed to migrate: " + err.Error())
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is caused by the unique constraint.
+func isUniqueViolation(err error) bool {
+	serr, ok := err.(sqlite3.Error)
+	return ok && serr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// isForeignKeyViolation reports whether err is caused by the foreign key
+// constraint.
+func isForeignKeyViolation(err error) bool {
+	serr, ok := err.(sqlite3.Error)
+	return ok && serr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// nullID makes zero ID NULL, so the row gets the new ID.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// txID returns the ID of the current transaction.
+func (s *Storage) txID() (int64, error) {
+	if s.info.id != 0 {
+		return s.info.id, nil
+	}
+
+	res, err := s.q.Exec(`INSERT INTO tx DEFAULT VALUES`)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// AUTOINCREMENT never reuses IDs, so the row is not needed.
+	_, err = s.q.Exec(`DELETE FROM tx WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+
+	s.info.id = id
+
+	return id, nil
+}
+
+// logChange records the change to the change log and makes the undo step
+// like log_change trigger of postgres storage.
+func (s *Storage) logChange(entityName string, graphID int64, entityID int64,
+	operation string, before interface{}, after interface{}) error {
+
+	txID, err := s.txID()
+	if err != nil {
+		return errors.New("failed to get transaction ID: " + err.Error())
+	}
+
+	if entityName != entity.EntityGraph && !s.info.history {
+		_, err = s.q.Exec(`
+			DELETE FROM history_step WHERE graph_id = ? AND undone
+		`, graphID)
+		if err != nil {
+			return errors.New("failed to discard undone steps: " +
+				err.Error())
+		}
+
+		_, err = s.q.Exec(`
+			INSERT OR IGNORE INTO history_step (graph_id, tx_id)
+			VALUES (?, ?)
+		`, graphID, txID)
+		if err != nil {
+			return errors.New("failed to add history step: " + err.Error())
+		}
+	}
+
+	var beforeData, afterData []byte
+
+	if before != nil {
+		beforeData, err = json.Marshal(before)
+		if err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		afterData, err = json.Marshal(after)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.q.Exec(`
+		INSERT INTO change (graph_id, entity, entity_id, operation, before,
+			after, actor, created_at, tx_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, graphID, entityName, entityID, operation, beforeData, afterData,
+		s.actor, s.info.startedAt, txID)
+	if err != nil {
+		return errors.New("failed to log change: " + err.Error())
+	}
+
+	return nil
+}
+
+func (s *Storage) Graph(graphID int64) (g entity.Graph, err error) {
+	err = s.q.QueryRowx(`SELECT * FROM graph WHERE id = ?`, graphID).
+		StructScan(&g)
+	if err == sql.ErrNoRows {
+		err = entity.ErrGraphNotFound
+	}
+	return
+}
+
+func (s *Storage) Graphs() (gs []entity.Graph, err error) {
+	err = s.q.Select(&gs, `SELECT * FROM graph ORDER BY name`)
+	return
+}
+
+func (s *Storage) AddGraph(g entity.Graph) (id int64, err error) {
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddGraph(g)
+			return
+		})
+		return
+	}
+
+	res, err := s.q.Exec(`INSERT INTO graph (name, directed) VALUES (?, ?)`,
+		g.Name, g.Directed)
+	if err != nil {
+		if isUniqueViolation(err) {
+			err = entity.ErrDuplicatedGraphName
+		}
+		return 0, err
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	g, err = s.Graph(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, s.logChange(entity.EntityGraph, id, id,
+		entity.OperationInsert, nil, g)
+}
+
+// CloneGraph copies the graph with its vertexes and edges into the new
+// graph with the given name. Copied vertexes and edges get new IDs.
+func (s *Storage) CloneGraph(graphID int64, name string) (id int64,
+	err error) {
+
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.CloneGraph(graphID, name)
+			return
+		})
+		return
+	}
+
+	g, err := s.Graph(graphID)
+	if err != nil {
+		return 0, err
+	}
+
+	g.Name = name
+
+	id, err = s.AddGraph(g)
+	if err != nil {
+		return 0, err
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		return 0, errors.New("failed to get vertexes: " + err.Error())
+	}
+
+	ids := make(map[int64]int64, len(vs))
+
+	for _, v := range vs {
+		oldID := v.ID
+		v.ID, v.GraphID, v.Version = 0, id, 1
+		v.ID, err = s.insertVertex(v)
+		if err != nil {
+			return 0, errors.New("failed to copy vertex: " + err.Error())
+		}
+		ids[oldID] = v.ID
+	}
+
+	es, err := s.Edges(graphID)
+	if err != nil {
+		return 0, errors.New("failed to get edges: " + err.Error())
+	}
+
+	for _, e := range es {
+		e.ID, e.GraphID, e.Version = 0, id, 1
+		e.From, e.To = ids[e.From], ids[e.To]
+		_, err = s.insertEdge(e)
+		if err != nil {
+			return 0, errors.New("failed to copy edge: " + err.Error())
+		}
+	}
+
+	return id, nil
+}
+
+// RemoveGraph removes the graph with its vertexes, edges and snapshots.
+func (s *Storage) RemoveGraph(graphID int64) (err error) {
+	if s.tx == nil {
+		return s.InTx(func(ts web.Storage) error {
+			return ts.RemoveGraph(graphID)
+		})
+	}
+
+	g, err := s.Graph(graphID)
+	if err != nil {
+		if err == entity.ErrGraphNotFound {
+			err = nil
+		}
+		return
+	}
+
+	es, err := s.Edges(graphID)
+	if err != nil {
+		return errors.New("failed to get edges: " + err.Error())
+	}
+
+	for _, e := range es {
+		err = s.removeEdge(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		return errors.New("failed to get vertexes: " + err.Error())
+	}
+
+	for _, v := range vs {
+		err = s.removeVertex(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Snapshots are removed by the foreign key.
+	_, err = s.q.Exec(`DELETE FROM graph WHERE id = ?`, graphID)
+	if err != nil {
+		return err
+	}
+
+	return s.logChange(entity.EntityGraph, graphID, graphID,
+		entity.OperationDelete, g, nil)
+}
+
+func (s *Storage) Vertex(vertexID int64) (v entity.Vertex, err error) {
+	err = s.q.QueryRowx(`SELECT * FROM vertex WHERE id = ?`,
+		vertexID).StructScan(&v)
+	if err == sql.ErrNoRows {
+		err = entity.ErrVertexNotFound
+	}
+	return
+}
+
+func (s *Storage) Vertexes(graphID int64) (vs []entity.Vertex, err error) {
+	err = s.q.Select(&vs, `
+		SELECT * FROM vertex WHERE graph_id = ? ORDER BY id
+	`, graphID)
+	return
+}
+
+func (s *Storage) AddVertex(v entity.Vertex) (id int64, err error) {
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddVertex(v)
+			return
+		})
+		return
+	}
+
+	v.ID, v.Version = 0, 1
+
+	id, err = s.insertVertex(v)
+	if isForeignKeyViolation(err) {
+		err = entity.ErrGraphNotFound
+	}
+	return
+}
+
+// insertVertex stores the vertex with its ID and version, zero ID is
+// replaced with the new one.
+func (s *Storage) insertVertex(v entity.Vertex) (int64, error) {
+	res, err := s.q.Exec(`
+		INSERT INTO vertex (id, graph_id, x, y, label, attributes, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, nullID(v.ID), v.GraphID, v.X, v.Y, v.Label, v.Attributes, v.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	v, err = s.Vertex(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, s.logChange(entity.EntityVertex, v.GraphID, id,
+		entity.OperationInsert, nil, v)
+}
+
+func (s *Storage) SetVertex(v entity.Vertex) (version int64, err error) {
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			version, err = ts.SetVertex(v)
+			return
+		})
+		return
+	}
+
+	old, err := s.Vertex(v.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if v.Version != 0 && v.Version != old.Version {
+		return 0, entity.ErrVersionConflict
+	}
+
+	return s.updateVertex(old, v)
+}
+
+// updateVertex updates the stored vertex old with coordinates, label and
+// attributes of v and returns its new version.
+func (s *Storage) updateVertex(old entity.Vertex, v entity.Vertex) (int64,
+	error) {
+
+	_, err := s.q.Exec(`
+		UPDATE vertex
+		SET x = ?, y = ?, label = ?, attributes = ?, version = version + 1
+		WHERE id = ?
+	`, v.X, v.Y, v.Label, v.Attributes, old.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := s.Vertex(old.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return updated.Version, s.logChange(entity.EntityVertex, old.GraphID,
+		old.ID, entity.OperationUpdate, old, updated)
+}
+
+// RemoveVertex removes the vertex with all its edges.
+func (s *Storage) RemoveVertex(vertexID int64) (err error) {
+	if s.tx == nil {
+		return s.InTx(func(ts web.Storage) error {
+			return ts.RemoveVertex(vertexID)
+		})
+	}
+
+	v, err := s.Vertex(vertexID)
+	if err != nil {
+		if err == entity.ErrVertexNotFound {
+			err = nil
+		}
+		return
+	}
+
+	return s.removeVertex(v)
+}
+
+func (s *Storage) removeVertex(v entity.Vertex) error {
+	var es []entity.Edge
+	err := s.q.Select(&es, `
+		SELECT `+edgeColumns+` FROM edge
+		WHERE "from" = ? OR "to" = ? ORDER BY id
+	`, v.ID, v.ID)
+	if err != nil {
+		return errors.New("failed to get vertex edges: " + err.Error())
+	}
+
+	for _, e := range es {
+		err = s.removeEdge(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.q.Exec(`DELETE FROM vertex WHERE id = ?`, v.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.logChange(entity.EntityVertex, v.GraphID, v.ID,
+		entity.OperationDelete, v, nil)
+}
+
+func (s *Storage) Edge(edgeID int64) (e entity.Edge, err error) {
+	err = s.q.QueryRowx(`SELECT `+edgeColumns+` FROM edge WHERE id = ?`,
+		edgeID).StructScan(&e)
+	if err == sql.ErrNoRows {
+		err = entity.ErrEdgeNotFound
+	}
+	return
+}
+
+func (s *Storage) Edges(graphID int64) (es []entity.Edge, err error) {
+	err = s.q.Select(&es, `
+		SELECT `+edgeColumns+` FROM edge WHERE graph_id = ? ORDER BY id
+	`, graphID)
+	return
+}
+
+func (s *Storage) AddEdge(e entity.Edge) (id int64, err error) {
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			id, err = ts.AddEdge(e)
+			return
+		})
+		return
+	}
+
+	e.ID, e.Version = 0, 1
+
+	id, err = s.insertEdge(e)
+	if isForeignKeyViolation(err) {
+		// SQLite does not tell which key is violated.
+		err = entity.ErrVertexNotFound
+		_, gerr := s.Graph(e.GraphID)
+		if gerr != nil {
+			err = gerr
+		}
+	}
+	return
+}
+
+// insertEdge stores the edge with its ID and version, zero ID is replaced
+// with the new one.
+func (s *Storage) insertEdge(e entity.Edge) (int64, error) {
+	res, err := s.q.Exec(`
+		INSERT INTO edge (id, graph_id, "from", "to", weight, directed,
+			label, attributes, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullID(e.ID), e.GraphID, e.From, e.To, e.Weight, !e.Undirected,
+		e.Label, e.Attributes, e.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	e, err = s.Edge(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, s.logChange(entity.EntityEdge, e.GraphID, id,
+		entity.OperationInsert, nil, e)
+}
+
+func (s *Storage) SetEdge(e entity.Edge) (version int64, err error) {
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			version, err = ts.SetEdge(e)
+			return
+		})
+		return
+	}
+
+	old, err := s.Edge(e.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if e.Version != 0 && e.Version != old.Version {
+		return 0, entity.ErrVersionConflict
+	}
+
+	return s.updateEdge(old, e)
+}
+
+// updateEdge updates the stored edge old with weight, direction, label and
+// attributes of e and returns its new version.
+func (s *Storage) updateEdge(old entity.Edge, e entity.Edge) (int64, error) {
+	_, err := s.q.Exec(`
+		UPDATE edge
+		SET weight = ?, directed = ?, label = ?, attributes = ?,
+			version = version + 1
+		WHERE id = ?
+	`, e.Weight, !e.Undirected, e.Label, e.Attributes, old.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := s.Edge(old.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return updated.Version, s.logChange(entity.EntityEdge, old.GraphID,
+		old.ID, entity.OperationUpdate, old, updated)
+}
+
+func (s *Storage) RemoveEdge(edgeID int64) (err error) {
+	if s.tx == nil {
+		return s.InTx(func(ts web.Storage) error {
+			return ts.RemoveEdge(edgeID)
+		})
+	}
+
+	e, err := s.Edge(edgeID)
+	if err != nil {
+		if err == entity.ErrEdgeNotFound {
+			err = nil
+		}
+		return
+	}
+
+	return s.removeEdge(e)
+}
+
+func (s *Storage) removeEdge(e entity.Edge) error {
+	_, err := s.q.Exec(`DELETE FROM edge WHERE id = ?`, e.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.logChange(entity.EntityEdge, e.GraphID, e.ID,
+		entity.OperationDelete, e, nil)
+}
+
+// changeRow is the change log record as it is stored. json.RawMessage can
+// not be scanned from NULL, so before and after are scanned as bytes.
+type changeRow struct {
+	entity.Change
+	Before []byte `db:"before"`
+	After  []byte `db:"after"`
+}
+
+// selectChanges returns change log records selected by query.
+func (s *Storage) selectChanges(query string, args ...interface{}) (
+	[]entity.Change, error) {
+
+	var rows []changeRow
+	err := s.q.Select(&rows, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs []entity.Change
+	for _, r := range rows {
+		c := r.Change
+		c.Before, c.After = r.Before, r.After
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+func (s *Storage) Changes(graphID int64, f entity.ChangeFilter) (
+	[]entity.Change, error) {
+
+	query := `SELECT * FROM change WHERE graph_id = ?`
+	args := []interface{}{graphID}
+
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		query += " AND " + cond
+	}
+
+	// Times are stored as UTC text, so they are compared as UTC text too.
+	if f.Entity != "" {
+		where("entity = ?", f.Entity)
+	}
+	if f.EntityID != 0 {
+		where("entity_id = ?", f.EntityID)
+	}
+	if !f.Since.IsZero() {
+		where("created_at >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		where("created_at < ?", f.Until.UTC())
+	}
+	if f.BeforeID != 0 {
+		where("id < ?", f.BeforeID)
+	}
+
+	query += ` ORDER BY id DESC`
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += ` LIMIT ?`
+	}
+
+	return s.selectChanges(query, args...)
+}
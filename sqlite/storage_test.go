@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/storagetest"
+	"github.com/dimuls/graph/web"
+)
+
+// openTestStorage opens and migrates the storage in the database file at
+// path.
+func openTestStorage(t *testing.T, path string) *Storage {
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		s.db.Close()
+		s.rdb.Close()
+	})
+
+	err = s.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	return s
+}
+
+func newTestStorage(t *testing.T) web.Storage {
+	return openTestStorage(t, filepath.Join(t.TempDir(), "graph.db"))
+}
+
+func TestStorage_conformance(t *testing.T) {
+	storagetest.Run(t, newTestStorage)
+}
+
+func TestStorage_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.db")
+
+	s := openTestStorage(t, path)
+
+	graphID, err := s.AddGraph(entity.Graph{Name: "g"})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+
+	vertexID, err := s.AddVertex(entity.Vertex{GraphID: graphID, X: 1})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+
+	s.db.Close()
+	s.rdb.Close()
+
+	// Migrations are applied once, so the second run keeps the data.
+	s = openTestStorage(t, path)
+
+	v, err := s.Vertex(vertexID)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+	if v.GraphID != graphID || v.X != 1 {
+		t.Errorf("Vertex() got = %+v, want vertex of graph %d with x = 1",
+			v, graphID)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Errorf("Undo() error = %v", err)
+	}
+
+	_, err = s.Vertex(vertexID)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() after undo error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+}
+
+func TestStorage_InReadTx(t *testing.T) {
+	s := openTestStorage(t, filepath.Join(t.TempDir(), "graph.db"))
+
+	graphID, err := s.AddGraph(entity.Graph{Name: "g"})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+
+	err = s.InReadTx(func(ts web.Storage) error {
+		vs, err := ts.Vertexes(graphID)
+		if err != nil {
+			return err
+		}
+
+		// Writer does not wait for the read transaction.
+		_, err = s.AddVertex(entity.Vertex{GraphID: graphID})
+		if err != nil {
+			return err
+		}
+
+		vs2, err := ts.Vertexes(graphID)
+		if err != nil {
+			return err
+		}
+		if len(vs) != 0 || len(vs2) != 0 {
+			t.Errorf("Vertexes() got = %+v and %+v, want no vertexes "+
+				"in snapshot", vs, vs2)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InReadTx() error = %v", err)
+	}
+}
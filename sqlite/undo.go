@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+)
+
+func (s *Storage) Undo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, true)
+}
+
+func (s *Storage) Redo(graphID int64) ([]entity.Change, error) {
+	return s.step(graphID, false)
+}
+
+type stateKey struct {
+	entity string
+	id     int64
+}
+
+// state is the state of vertex or edge to restore. Nil data means that
+// the entity should not exist.
+type state struct {
+	stateKey
+	data json.RawMessage
+}
+
+// step undoes or redoes the history step of the graph. Transactions take
+// the write lock at once, so concurrent undo and redo are serialized.
+func (s *Storage) step(graphID int64, undo bool) (
+	cs []entity.Change, err error) {
+
+	if s.tx == nil {
+		err = s.InTx(func(ts web.Storage) (err error) {
+			cs, err = ts.(*Storage).step(graphID, undo)
+			return
+		})
+		return
+	}
+
+	_, err = s.Graph(graphID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		stepID int64
+		txID   int64
+		query  string
+		mode   string
+	)
+
+	if undo {
+		query = `
+			SELECT id, tx_id FROM history_step
+			WHERE graph_id = ? AND NOT undone
+			ORDER BY id DESC LIMIT 1
+		`
+		mode = "undo"
+	} else {
+		query = `
+			SELECT id, tx_id FROM history_step
+			WHERE graph_id = ? AND undone
+			ORDER BY id LIMIT 1
+		`
+		mode = "redo"
+	}
+
+	err = s.q.QueryRow(query, graphID).Scan(&stepID, &txID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if undo {
+				err = entity.ErrNothingToUndo
+			} else {
+				err = entity.ErrNothingToRedo
+			}
+		}
+		return
+	}
+
+	// Changes made in history mode do not make new steps.
+	s.info.history = true
+
+	changes, err := s.selectChanges(`
+		SELECT * FROM change
+		WHERE graph_id = ? AND tx_id = ? AND entity <> ?
+		ORDER BY id
+	`, graphID, txID, entity.EntityGraph)
+	if err != nil {
+		return nil, errors.New("failed to get step changes: " + err.Error())
+	}
+
+	// Undo restores the state before the first change of every entity in
+	// the step and redo restores the state after the last one.
+	var (
+		states []*state
+		index  = map[stateKey]*state{}
+	)
+	for _, c := range changes {
+		key := stateKey{entity: c.Entity, id: c.EntityID}
+		st, exists := index[key]
+		if !exists {
+			st = &state{stateKey: key, data: c.Before}
+			index[key] = st
+			states = append(states, st)
+		}
+		if !undo {
+			st.data = c.After
+		}
+	}
+
+	// Edges are deleted before vertexes and restored after them so that
+	// foreign keys hold.
+	for _, a := range []struct {
+		entity  string
+		deleted bool
+	}{
+		{entity.EntityEdge, true},
+		{entity.EntityVertex, true},
+		{entity.EntityVertex, false},
+		{entity.EntityEdge, false},
+	} {
+		for _, st := range states {
+			if st.entity != a.entity || (st.data == nil) != a.deleted {
+				continue
+			}
+			err = s.restoreState(st)
+			if err != nil {
+				return nil, errors.New("failed to apply " + mode + ": " +
+					err.Error())
+			}
+		}
+	}
+
+	_, err = s.q.Exec(`UPDATE history_step SET undone = ? WHERE id = ?`,
+		undo, stepID)
+	if err != nil {
+		return nil, errors.New("failed to update history step: " +
+			err.Error())
+	}
+
+	curTxID, err := s.txID()
+	if err != nil {
+		return nil, errors.New("failed to get transaction ID: " +
+			err.Error())
+	}
+
+	cs, err = s.selectChanges(`
+		SELECT * FROM change
+		WHERE graph_id = ? AND tx_id = ?
+		ORDER BY id
+	`, graphID, curTxID)
+	if err != nil {
+		return nil, errors.New("failed to get changes: " + err.Error())
+	}
+
+	return cs, nil
+}
+
+// restoreState removes the vertex or edge, updates it or inserts it with
+// its original ID.
+func (s *Storage) restoreState(st *state) error {
+	switch st.entity {
+	case entity.EntityVertex:
+		if st.data == nil {
+			return s.RemoveVertex(st.id)
+		}
+		var v entity.Vertex
+		err := json.Unmarshal(st.data, &v)
+		if err != nil {
+			return err
+		}
+		old, err := s.Vertex(v.ID)
+		if err == entity.ErrVertexNotFound {
+			_, err = s.insertVertex(v)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		_, err = s.updateVertex(old, v)
+		return err
+
+	case entity.EntityEdge:
+		if st.data == nil {
+			return s.RemoveEdge(st.id)
+		}
+		var e entity.Edge
+		err := json.Unmarshal(st.data, &e)
+		if err != nil {
+			return err
+		}
+		old, err := s.Edge(e.ID)
+		if err == entity.ErrEdgeNotFound {
+			_, err = s.insertEdge(e)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		_, err = s.updateEdge(old, e)
+		return err
+	}
+
+	return nil
+}
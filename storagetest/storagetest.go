@@ -0,0 +1,1217 @@
+// Package storagetest implements the conformance test suite for web.Storage
+// implementations. The suite defines the behaviour every backend must
+// follow: entity errors, versioning, cascade deletes, transactions, change
+// log, undo and redo, and snapshots.
+package storagetest
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/web"
+)
+
+// Factory returns new empty storage. It is called for every test.
+type Factory func(t *testing.T) web.Storage
+
+// Run runs the conformance tests against storages made by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s web.Storage)
+	}{
+		{"Graphs", testGraphs},
+		{"RemoveGraph", testRemoveGraph},
+		{"CloneGraph", testCloneGraph},
+		{"Vertexes", testVertexes},
+		{"SetVertex", testSetVertex},
+		{"RemoveVertex", testRemoveVertex},
+		{"Edges", testEdges},
+		{"SetEdge", testSetEdge},
+		{"InTx", testInTx},
+		{"InReadTx", testInReadTx},
+		{"Changes", testChanges},
+		{"EdgeChanges", testEdgeChanges},
+		{"UndoRedo", testUndoRedo},
+		{"UndoRemoveVertex", testUndoRemoveVertex},
+		{"UndoRemoveEdge", testUndoRemoveEdge},
+		{"Snapshots", testSnapshots},
+		{"RestoreSnapshot", testRestoreSnapshot},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// unknownID is the ID which no graph, vertex, edge or snapshot has.
+const unknownID = 1 << 40
+
+func addGraph(t *testing.T, s web.Storage, name string) int64 {
+	t.Helper()
+	id, err := s.AddGraph(entity.Graph{Name: name, Directed: true})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+	return id
+}
+
+func addVertex(t *testing.T, s web.Storage, graphID int64,
+	x float64, y float64) int64 {
+
+	t.Helper()
+	id, err := s.AddVertex(entity.Vertex{GraphID: graphID, X: x, Y: y})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+	return id
+}
+
+func addEdge(t *testing.T, s web.Storage, graphID int64, from int64,
+	to int64, weight float64) int64 {
+
+	t.Helper()
+	id, err := s.AddEdge(entity.Edge{
+		GraphID: graphID,
+		From:    from,
+		To:      to,
+		Weight:  weight,
+	})
+	if err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+	return id
+}
+
+func vertexes(t *testing.T, s web.Storage, graphID int64) []entity.Vertex {
+	t.Helper()
+	vs, err := s.Vertexes(graphID)
+	if err != nil {
+		t.Fatalf("Vertexes() error = %v", err)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].ID < vs[j].ID })
+	return vs
+}
+
+func edges(t *testing.T, s web.Storage, graphID int64) []entity.Edge {
+	t.Helper()
+	es, err := s.Edges(graphID)
+	if err != nil {
+		t.Fatalf("Edges() error = %v", err)
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].ID < es[j].ID })
+	return es
+}
+
+func vertexX(t *testing.T, s web.Storage, vertexID int64) float64 {
+	t.Helper()
+	v, err := s.Vertex(vertexID)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+	return v.X
+}
+
+func testGraphs(t *testing.T, s web.Storage) {
+	gs, err := s.Graphs()
+	if err != nil {
+		t.Fatalf("Graphs() error = %v", err)
+	}
+	if len(gs) != 0 {
+		t.Fatalf("Graphs() got = %+v, want none", gs)
+	}
+
+	b := addGraph(t, s, "b")
+
+	a, err := s.AddGraph(entity.Graph{Name: "a", Directed: false})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+	if a == b {
+		t.Fatalf("AddGraph() returned the same ID %d twice", a)
+	}
+
+	g, err := s.Graph(a)
+	if err != nil {
+		t.Fatalf("Graph() error = %v", err)
+	}
+	want := entity.Graph{ID: a, Name: "a", Directed: false, Version: 1}
+	if g != want {
+		t.Errorf("Graph() got = %+v, want %+v", g, want)
+	}
+
+	gs, err = s.Graphs()
+	if err != nil {
+		t.Fatalf("Graphs() error = %v", err)
+	}
+	if len(gs) != 2 || gs[0].ID != a || gs[1].ID != b {
+		t.Errorf("Graphs() got = %+v, want graphs a and b ordered by name",
+			gs)
+	}
+
+	_, err = s.AddGraph(entity.Graph{Name: "a"})
+	if err != entity.ErrDuplicatedGraphName {
+		t.Errorf("AddGraph() error = %v, want %v", err,
+			entity.ErrDuplicatedGraphName)
+	}
+
+	_, err = s.Graph(unknownID)
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("Graph() error = %v, want %v", err, entity.ErrGraphNotFound)
+	}
+}
+
+func testRemoveGraph(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	otherID := addGraph(t, s, "other")
+
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 1)
+	e := addEdge(t, s, graphID, v1, v2, 1)
+	other := addVertex(t, s, otherID, 0, 0)
+
+	_, err := s.AddSnapshot(graphID, "s")
+	if err != nil {
+		t.Fatalf("AddSnapshot() error = %v", err)
+	}
+
+	err = s.RemoveGraph(graphID)
+	if err != nil {
+		t.Fatalf("RemoveGraph() error = %v", err)
+	}
+
+	_, err = s.Graph(graphID)
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("Graph() error = %v, want %v", err, entity.ErrGraphNotFound)
+	}
+	_, err = s.Vertex(v1)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+	_, err = s.Edge(e)
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("Edge() error = %v, want %v", err, entity.ErrEdgeNotFound)
+	}
+	if vs := vertexes(t, s, graphID); len(vs) != 0 {
+		t.Errorf("Vertexes() got = %+v, want none", vs)
+	}
+	if es := edges(t, s, graphID); len(es) != 0 {
+		t.Errorf("Edges() got = %+v, want none", es)
+	}
+
+	ss, err := s.Snapshots(graphID)
+	if err != nil {
+		t.Fatalf("Snapshots() error = %v", err)
+	}
+	if len(ss) != 0 {
+		t.Errorf("Snapshots() got = %+v, want none", ss)
+	}
+
+	_, err = s.Vertex(other)
+	if err != nil {
+		t.Errorf("Vertex() of other graph error = %v", err)
+	}
+
+	err = s.RemoveGraph(unknownID)
+	if err != nil {
+		t.Errorf("RemoveGraph() of unknown graph error = %v", err)
+	}
+}
+
+func testCloneGraph(t *testing.T, s web.Storage) {
+	graphID, err := s.AddGraph(entity.Graph{Name: "g", Directed: false})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+
+	v1, err := s.AddVertex(entity.Vertex{
+		GraphID:    graphID,
+		X:          1,
+		Y:          2,
+		Label:      "a",
+		Attributes: entity.Attributes{"color": "red"},
+	})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+	v2 := addVertex(t, s, graphID, 3, 4)
+	addEdge(t, s, graphID, v1, v2, 5)
+
+	cloneID, err := s.CloneGraph(graphID, "clone")
+	if err != nil {
+		t.Fatalf("CloneGraph() error = %v", err)
+	}
+
+	g, err := s.Graph(cloneID)
+	if err != nil {
+		t.Fatalf("Graph() error = %v", err)
+	}
+	if g.Name != "clone" || g.Directed {
+		t.Errorf("Graph() got = %+v, want undirected clone", g)
+	}
+
+	vs, cvs := vertexes(t, s, graphID), vertexes(t, s, cloneID)
+	if len(cvs) != len(vs) {
+		t.Fatalf("Vertexes() of clone got = %+v, want %d vertexes", cvs,
+			len(vs))
+	}
+
+	// Copies are matched by coordinates since the order of new IDs is
+	// not defined.
+	ids := map[int64]int64{}
+	for _, cv := range cvs {
+		for _, v := range vs {
+			if cv.X != v.X || cv.Y != v.Y {
+				continue
+			}
+			if cv.ID == v.ID || cv.GraphID != cloneID ||
+				cv.Label != v.Label ||
+				!reflect.DeepEqual(cv.Attributes, v.Attributes) {
+				t.Errorf("Vertexes() of clone got = %+v, want copy of "+
+					"%+v with new ID", cv, v)
+			}
+			ids[v.ID] = cv.ID
+		}
+	}
+	if len(ids) != len(vs) {
+		t.Fatalf("Vertexes() of clone got = %+v, want copies of %+v", cvs,
+			vs)
+	}
+
+	es, ces := edges(t, s, graphID), edges(t, s, cloneID)
+	if len(ces) != 1 {
+		t.Fatalf("Edges() of clone got = %+v, want 1 edge", ces)
+	}
+	if ces[0].ID == es[0].ID || ces[0].From != ids[es[0].From] ||
+		ces[0].To != ids[es[0].To] || ces[0].Weight != es[0].Weight {
+		t.Errorf("Edges() of clone got = %+v, want copy of %+v", ces[0],
+			es[0])
+	}
+
+	_, err = s.CloneGraph(graphID, "clone")
+	if err != entity.ErrDuplicatedGraphName {
+		t.Errorf("CloneGraph() error = %v, want %v", err,
+			entity.ErrDuplicatedGraphName)
+	}
+
+	_, err = s.CloneGraph(unknownID, "unknown")
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("CloneGraph() error = %v, want %v", err,
+			entity.ErrGraphNotFound)
+	}
+}
+
+func testVertexes(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+
+	id, err := s.AddVertex(entity.Vertex{
+		GraphID:    graphID,
+		X:          1.5,
+		Y:          -2,
+		Label:      "a",
+		Attributes: entity.Attributes{"color": "red", "size": 2.5},
+	})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+
+	v, err := s.Vertex(id)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+	want := entity.Vertex{
+		ID:         id,
+		GraphID:    graphID,
+		X:          1.5,
+		Y:          -2,
+		Label:      "a",
+		Attributes: entity.Attributes{"color": "red", "size": 2.5},
+		Version:    1,
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Vertex() got = %+v, want %+v", v, want)
+	}
+
+	id2 := addVertex(t, s, graphID, 0, 0)
+
+	v, err = s.Vertex(id2)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+	if len(v.Attributes) != 0 || v.Label != "" {
+		t.Errorf("Vertex() got = %+v, want no label and attributes", v)
+	}
+
+	vs := vertexes(t, s, graphID)
+	if len(vs) != 2 || vs[0].ID != id || vs[1].ID != id2 {
+		t.Errorf("Vertexes() got = %+v, want vertexes %d and %d", vs, id,
+			id2)
+	}
+
+	if vs := vertexes(t, s, unknownID); len(vs) != 0 {
+		t.Errorf("Vertexes() of unknown graph got = %+v, want none", vs)
+	}
+
+	_, err = s.Vertex(unknownID)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+
+	_, err = s.AddVertex(entity.Vertex{GraphID: unknownID})
+	if !errors.Is(err, entity.ErrGraphNotFound) {
+		t.Errorf("AddVertex() to unknown graph error = %v, want %v", err,
+			entity.ErrGraphNotFound)
+	}
+}
+
+func testSetVertex(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	id := addVertex(t, s, graphID, 0, 0)
+
+	version, err := s.SetVertex(entity.Vertex{
+		ID:         id,
+		X:          3,
+		Y:          4,
+		Label:      "b",
+		Attributes: entity.Attributes{"n": 1.0},
+	})
+	if err != nil {
+		t.Fatalf("SetVertex() error = %v", err)
+	}
+	if version != 2 {
+		t.Errorf("SetVertex() got version %d, want 2", version)
+	}
+
+	v, err := s.Vertex(id)
+	if err != nil {
+		t.Fatalf("Vertex() error = %v", err)
+	}
+	want := entity.Vertex{
+		ID:         id,
+		GraphID:    graphID,
+		X:          3,
+		Y:          4,
+		Label:      "b",
+		Attributes: entity.Attributes{"n": 1.0},
+		Version:    2,
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Vertex() got = %+v, want %+v", v, want)
+	}
+
+	v.X = 5
+	version, err = s.SetVertex(v)
+	if err != nil {
+		t.Fatalf("SetVertex() with current version error = %v", err)
+	}
+	if version != 3 {
+		t.Errorf("SetVertex() got version %d, want 3", version)
+	}
+
+	_, err = s.SetVertex(v)
+	if err != entity.ErrVersionConflict {
+		t.Errorf("SetVertex() with stale version error = %v, want %v", err,
+			entity.ErrVersionConflict)
+	}
+	if x := vertexX(t, s, id); x != 5 {
+		t.Errorf("Vertex() got x = %v after conflict, want 5", x)
+	}
+
+	_, err = s.SetVertex(entity.Vertex{ID: unknownID})
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("SetVertex() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+}
+
+func testRemoveVertex(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	v3 := addVertex(t, s, graphID, 2, 0)
+	e12 := addEdge(t, s, graphID, v1, v2, 1)
+	e21 := addEdge(t, s, graphID, v2, v1, 1)
+	e13 := addEdge(t, s, graphID, v1, v3, 1)
+
+	err := s.RemoveVertex(v2)
+	if err != nil {
+		t.Fatalf("RemoveVertex() error = %v", err)
+	}
+
+	_, err = s.Vertex(v2)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+
+	for _, id := range []int64{e12, e21} {
+		_, err = s.Edge(id)
+		if err != entity.ErrEdgeNotFound {
+			t.Errorf("Edge() of removed vertex edge error = %v, want %v",
+				err, entity.ErrEdgeNotFound)
+		}
+	}
+
+	es := edges(t, s, graphID)
+	if len(es) != 1 || es[0].ID != e13 {
+		t.Errorf("Edges() got = %+v, want edge %d", es, e13)
+	}
+
+	err = s.RemoveVertex(unknownID)
+	if err != nil {
+		t.Errorf("RemoveVertex() of unknown vertex error = %v", err)
+	}
+}
+
+func testEdges(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+
+	id, err := s.AddEdge(entity.Edge{
+		GraphID:    graphID,
+		From:       v1,
+		To:         v2,
+		Weight:     -1.5,
+		Undirected: true,
+		Label:      "e",
+		Attributes: entity.Attributes{"lanes": 2.0},
+	})
+	if err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+
+	e, err := s.Edge(id)
+	if err != nil {
+		t.Fatalf("Edge() error = %v", err)
+	}
+	want := entity.Edge{
+		ID:         id,
+		GraphID:    graphID,
+		From:       v1,
+		To:         v2,
+		Weight:     -1.5,
+		Undirected: true,
+		Label:      "e",
+		Attributes: entity.Attributes{"lanes": 2.0},
+		Version:    1,
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("Edge() got = %+v, want %+v", e, want)
+	}
+
+	loop := addEdge(t, s, graphID, v1, v1, 0)
+
+	es := edges(t, s, graphID)
+	if len(es) != 2 || es[0].ID != id || es[1].ID != loop {
+		t.Errorf("Edges() got = %+v, want edges %d and %d", es, id, loop)
+	}
+
+	_, err = s.Edge(unknownID)
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("Edge() error = %v, want %v", err, entity.ErrEdgeNotFound)
+	}
+
+	_, err = s.AddEdge(entity.Edge{GraphID: graphID, From: v1,
+		To: unknownID})
+	if !errors.Is(err, entity.ErrVertexNotFound) {
+		t.Errorf("AddEdge() to unknown vertex error = %v, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+
+	_, err = s.AddEdge(entity.Edge{GraphID: unknownID, From: v1, To: v1})
+	if !errors.Is(err, entity.ErrGraphNotFound) {
+		t.Errorf("AddEdge() to unknown graph error = %v, want %v", err,
+			entity.ErrGraphNotFound)
+	}
+
+	err = s.RemoveEdge(id)
+	if err != nil {
+		t.Fatalf("RemoveEdge() error = %v", err)
+	}
+
+	_, err = s.Edge(id)
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("Edge() error = %v, want %v", err, entity.ErrEdgeNotFound)
+	}
+	if vs := vertexes(t, s, graphID); len(vs) != 2 {
+		t.Errorf("Vertexes() got = %+v, want vertexes kept", vs)
+	}
+
+	err = s.RemoveEdge(unknownID)
+	if err != nil {
+		t.Errorf("RemoveEdge() of unknown edge error = %v", err)
+	}
+}
+
+func testSetEdge(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	id := addEdge(t, s, graphID, v1, v2, 1)
+
+	// Endpoints can not be changed.
+	version, err := s.SetEdge(entity.Edge{
+		ID:         id,
+		From:       v2,
+		To:         v1,
+		Weight:     7,
+		Undirected: true,
+		Label:      "e",
+	})
+	if err != nil {
+		t.Fatalf("SetEdge() error = %v", err)
+	}
+	if version != 2 {
+		t.Errorf("SetEdge() got version %d, want 2", version)
+	}
+
+	e, err := s.Edge(id)
+	if err != nil {
+		t.Fatalf("Edge() error = %v", err)
+	}
+	if e.From != v1 || e.To != v2 || e.Weight != 7 || !e.Undirected ||
+		e.Label != "e" || e.Version != 2 {
+		t.Errorf("Edge() got = %+v, want updated weight, direction and "+
+			"label only", e)
+	}
+
+	e.Version = 1
+	_, err = s.SetEdge(e)
+	if err != entity.ErrVersionConflict {
+		t.Errorf("SetEdge() with stale version error = %v, want %v", err,
+			entity.ErrVersionConflict)
+	}
+
+	_, err = s.SetEdge(entity.Edge{ID: unknownID})
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("SetEdge() error = %v, want %v", err,
+			entity.ErrEdgeNotFound)
+	}
+}
+
+func testInTx(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+
+	var v1 int64
+
+	err := s.InTx(func(ts web.Storage) (err error) {
+		v1, err = ts.AddVertex(entity.Vertex{GraphID: graphID})
+		if err != nil {
+			return err
+		}
+		// Changes are visible inside the transaction.
+		_, err = ts.Vertex(v1)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	_, err = s.Vertex(v1)
+	if err != nil {
+		t.Errorf("Vertex() after commit error = %v", err)
+	}
+
+	errTest := errors.New("test")
+
+	var v2 int64
+
+	err = s.InTx(func(ts web.Storage) error {
+		_, err := ts.AddGraph(entity.Graph{Name: "h"})
+		if err != nil {
+			return err
+		}
+		// Nested transactions are parts of the outer one.
+		err = ts.InTx(func(ts web.Storage) (err error) {
+			v2, err = ts.AddVertex(entity.Vertex{GraphID: graphID})
+			return
+		})
+		if err != nil {
+			return err
+		}
+		err = ts.RemoveVertex(v1)
+		if err != nil {
+			return err
+		}
+		return errTest
+	})
+	if err != errTest {
+		t.Fatalf("InTx() error = %v, want %v", err, errTest)
+	}
+
+	gs, err := s.Graphs()
+	if err != nil {
+		t.Fatalf("Graphs() error = %v", err)
+	}
+	if len(gs) != 1 {
+		t.Errorf("Graphs() got = %+v after rollback, want 1 graph", gs)
+	}
+
+	_, err = s.Vertex(v1)
+	if err != nil {
+		t.Errorf("Vertex() after rollback error = %v", err)
+	}
+
+	_, err = s.Vertex(v2)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() added in rolled back transaction error = %v, "+
+			"want %v", err, entity.ErrVertexNotFound)
+	}
+}
+
+func testInReadTx(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v := addVertex(t, s, graphID, 0, 0)
+
+	err := s.InReadTx(func(ts web.Storage) error {
+		vs, err := ts.Vertexes(graphID)
+		if err != nil {
+			return err
+		}
+		if len(vs) != 1 || vs[0].ID != v {
+			t.Errorf("Vertexes() got = %+v, want vertex %d", vs, v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InReadTx() error = %v", err)
+	}
+
+	err = s.InReadTx(func(ts web.Storage) error {
+		_, err := ts.AddVertex(entity.Vertex{GraphID: graphID})
+		return err
+	})
+	if err == nil {
+		t.Errorf("InReadTx() with AddVertex() error = nil, want error")
+	}
+
+	if vs := vertexes(t, s, graphID); len(vs) != 1 {
+		t.Errorf("Vertexes() got = %+v after read transaction, "+
+			"want 1 vertex", vs)
+	}
+}
+
+func testChanges(t *testing.T, s web.Storage) {
+	as := s.WithActor("alice")
+
+	graphID, err := as.AddGraph(entity.Graph{Name: "g"})
+	if err != nil {
+		t.Fatalf("AddGraph() error = %v", err)
+	}
+
+	v1 := addVertex(t, as, graphID, 0, 0)
+
+	var v2 int64
+	err = as.InTx(func(ts web.Storage) (err error) {
+		_, err = ts.SetVertex(entity.Vertex{ID: v1, X: 1})
+		if err != nil {
+			return
+		}
+		v2, err = ts.AddVertex(entity.Vertex{GraphID: graphID})
+		return
+	})
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	err = s.RemoveVertex(v1)
+	if err != nil {
+		t.Fatalf("RemoveVertex() error = %v", err)
+	}
+
+	cs, err := s.Changes(graphID, entity.ChangeFilter{})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+
+	type change struct {
+		entity    string
+		entityID  int64
+		operation string
+		actor     string
+	}
+
+	var got []change
+	for _, c := range cs {
+		got = append(got, change{c.Entity, c.EntityID, c.Operation,
+			c.Actor})
+	}
+
+	want := []change{
+		{entity.EntityVertex, v1, entity.OperationDelete, ""},
+		{entity.EntityVertex, v2, entity.OperationInsert, "alice"},
+		{entity.EntityVertex, v1, entity.OperationUpdate, "alice"},
+		{entity.EntityVertex, v1, entity.OperationInsert, "alice"},
+		{entity.EntityGraph, graphID, entity.OperationInsert, "alice"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Changes() got = %+v, want %+v", got, want)
+	}
+
+	for i := 1; i < len(cs); i++ {
+		if cs[i].ID >= cs[i-1].ID {
+			t.Errorf("Changes() got IDs %d and %d, want newest first",
+				cs[i-1].ID, cs[i].ID)
+		}
+	}
+
+	if cs[1].TxID != cs[2].TxID || cs[2].TxID == cs[3].TxID {
+		t.Errorf("Changes() got transaction IDs %d, %d, %d, want the "+
+			"same ID only for changes of one transaction", cs[1].TxID,
+			cs[2].TxID, cs[3].TxID)
+	}
+
+	var before, after entity.Vertex
+	if json.Unmarshal(cs[2].Before, &before) != nil ||
+		json.Unmarshal(cs[2].After, &after) != nil ||
+		before.X != 0 || after.X != 1 || after.Version != 2 {
+		t.Errorf("Changes() got update before = %s, after = %s",
+			cs[2].Before, cs[2].After)
+	}
+	if cs[0].After != nil || cs[3].Before != nil {
+		t.Errorf("Changes() got delete after = %s, insert before = %s, "+
+			"want null", cs[0].After, cs[3].Before)
+	}
+	if cs[0].CreatedAt.IsZero() {
+		t.Errorf("Changes() got zero created_at")
+	}
+
+	filtered, err := s.Changes(graphID, entity.ChangeFilter{
+		Entity:   entity.EntityVertex,
+		EntityID: v1,
+		BeforeID: cs[0].ID,
+		Limit:    1,
+	})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != cs[2].ID {
+		t.Errorf("Changes() with filter got = %+v, want update of vertex "+
+			"%d", filtered, v1)
+	}
+
+	filtered, err = s.Changes(graphID, entity.ChangeFilter{
+		Since: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(filtered) != 0 {
+		t.Errorf("Changes() since future got = %+v, want none", filtered)
+	}
+
+	filtered, err = s.Changes(graphID, entity.ChangeFilter{
+		Until: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(filtered) != len(cs) {
+		t.Errorf("Changes() until future got %d changes, want %d",
+			len(filtered), len(cs))
+	}
+}
+
+func testEdgeChanges(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	id := addEdge(t, s, graphID, v1, v2, 1)
+
+	_, err := s.SetEdge(entity.Edge{ID: id, Weight: 2, Undirected: true})
+	if err != nil {
+		t.Fatalf("SetEdge() error = %v", err)
+	}
+
+	err = s.RemoveEdge(id)
+	if err != nil {
+		t.Fatalf("RemoveEdge() error = %v", err)
+	}
+
+	cs, err := s.Changes(graphID, entity.ChangeFilter{
+		Entity:   entity.EntityEdge,
+		EntityID: id,
+	})
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+
+	var operations []string
+	for _, c := range cs {
+		operations = append(operations, c.Operation)
+	}
+	wantOperations := []string{entity.OperationDelete,
+		entity.OperationUpdate, entity.OperationInsert}
+	if !reflect.DeepEqual(operations, wantOperations) {
+		t.Fatalf("Changes() got operations %v, want %v", operations,
+			wantOperations)
+	}
+
+	var inserted, updated, deleted entity.Edge
+	if cs[2].Before != nil || json.Unmarshal(cs[2].After, &inserted) != nil ||
+		inserted.Weight != 1 || inserted.Undirected {
+		t.Errorf("Changes() got insert before = %s, after = %s",
+			cs[2].Before, cs[2].After)
+	}
+	if json.Unmarshal(cs[1].After, &updated) != nil ||
+		updated.Weight != 2 || !updated.Undirected {
+		t.Errorf("Changes() got update after = %s", cs[1].After)
+	}
+	if cs[0].After != nil || json.Unmarshal(cs[0].Before, &deleted) != nil ||
+		deleted.From != v1 || deleted.To != v2 || deleted.Weight != 2 {
+		t.Errorf("Changes() got delete before = %s, after = %s",
+			cs[0].Before, cs[0].After)
+	}
+}
+
+func testUndoRedo(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+
+	_, err := s.Undo(graphID)
+	if err != entity.ErrNothingToUndo {
+		t.Errorf("Undo() error = %v, want %v", err, entity.ErrNothingToUndo)
+	}
+	_, err = s.Redo(graphID)
+	if err != entity.ErrNothingToRedo {
+		t.Errorf("Redo() error = %v, want %v", err, entity.ErrNothingToRedo)
+	}
+	_, err = s.Undo(unknownID)
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("Undo() error = %v, want %v", err, entity.ErrGraphNotFound)
+	}
+
+	id := addVertex(t, s, graphID, 1, 0)
+
+	_, err = s.SetVertex(entity.Vertex{ID: id, X: 2})
+	if err != nil {
+		t.Fatalf("SetVertex() error = %v", err)
+	}
+
+	cs, err := s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(cs) == 0 {
+		t.Errorf("Undo() got no changes")
+	}
+	if x := vertexX(t, s, id); x != 1 {
+		t.Errorf("Vertex() got x = %v after undo, want 1", x)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	_, err = s.Vertex(id)
+	if err != entity.ErrVertexNotFound {
+		t.Errorf("Vertex() error = %v after undo, want %v", err,
+			entity.ErrVertexNotFound)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != entity.ErrNothingToUndo {
+		t.Errorf("Undo() error = %v, want %v", err, entity.ErrNothingToUndo)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if x := vertexX(t, s, id); x != 1 {
+		t.Errorf("Vertex() got x = %v after redo, want 1", x)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if x := vertexX(t, s, id); x != 2 {
+		t.Errorf("Vertex() got x = %v after redo, want 2", x)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != entity.ErrNothingToRedo {
+		t.Errorf("Redo() error = %v, want %v", err, entity.ErrNothingToRedo)
+	}
+
+	// Transaction is one step and new changes discard undone steps.
+	err = s.InTx(func(ts web.Storage) error {
+		_, err := ts.SetVertex(entity.Vertex{ID: id, X: 3})
+		if err != nil {
+			return err
+		}
+		_, err = ts.AddVertex(entity.Vertex{GraphID: graphID})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if x := vertexX(t, s, id); x != 2 {
+		t.Errorf("Vertex() got x = %v after undo, want 2", x)
+	}
+	if vs := vertexes(t, s, graphID); len(vs) != 1 {
+		t.Errorf("Vertexes() got = %+v after undo, want 1 vertex", vs)
+	}
+
+	_, err = s.SetVertex(entity.Vertex{ID: id, X: 4})
+	if err != nil {
+		t.Fatalf("SetVertex() error = %v", err)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != entity.ErrNothingToRedo {
+		t.Errorf("Redo() after new change error = %v, want %v", err,
+			entity.ErrNothingToRedo)
+	}
+}
+
+func testUndoRemoveVertex(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	e := addEdge(t, s, graphID, v1, v2, 3)
+
+	vs, es := vertexes(t, s, graphID), edges(t, s, graphID)
+
+	err := s.RemoveVertex(v2)
+	if err != nil {
+		t.Fatalf("RemoveVertex() error = %v", err)
+	}
+
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	if got := vertexes(t, s, graphID); !reflect.DeepEqual(got, vs) {
+		t.Errorf("Vertexes() got = %+v after undo, want %+v", got, vs)
+	}
+	if got := edges(t, s, graphID); !reflect.DeepEqual(got, es) {
+		t.Errorf("Edges() got = %+v after undo, want %+v", got, es)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+
+	_, err = s.Edge(e)
+	if err != entity.ErrEdgeNotFound {
+		t.Errorf("Edge() error = %v after redo, want %v", err,
+			entity.ErrEdgeNotFound)
+	}
+}
+
+func testUndoRemoveEdge(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	e := addEdge(t, s, graphID, v1, v2, 3)
+
+	es := edges(t, s, graphID)
+
+	err := s.RemoveEdge(e)
+	if err != nil {
+		t.Fatalf("RemoveEdge() error = %v", err)
+	}
+
+	cs, err := s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(cs) != 1 || cs[0].Operation != entity.OperationInsert ||
+		cs[0].Before != nil {
+		t.Errorf("Undo() got = %+v, want insert of edge %d", cs, e)
+	}
+	if got := edges(t, s, graphID); !reflect.DeepEqual(got, es) {
+		t.Errorf("Edges() got = %+v after undo, want %+v", got, es)
+	}
+
+	cs, err = s.Redo(graphID)
+	if err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if len(cs) != 1 || cs[0].Operation != entity.OperationDelete ||
+		cs[0].After != nil {
+		t.Errorf("Redo() got = %+v, want delete of edge %d", cs, e)
+	}
+	if got := edges(t, s, graphID); len(got) != 0 {
+		t.Errorf("Edges() got = %+v after redo, want none", got)
+	}
+
+	// Steps before the removal are undone too.
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	_, err = s.Undo(graphID)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if got := edges(t, s, graphID); len(got) != 0 {
+		t.Errorf("Edges() got = %+v after undo of add, want none", got)
+	}
+
+	_, err = s.Redo(graphID)
+	if err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if got := edges(t, s, graphID); !reflect.DeepEqual(got, es) {
+		t.Errorf("Edges() got = %+v after redo of add, want %+v", got, es)
+	}
+}
+
+func testSnapshots(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	otherID := addGraph(t, s, "other")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	addEdge(t, s, graphID, v1, v2, 1)
+
+	first, err := s.AddSnapshot(graphID, "first")
+	if err != nil {
+		t.Fatalf("AddSnapshot() error = %v", err)
+	}
+	if first.GraphID != graphID || first.Name != "first" ||
+		first.VertexCount != 2 || first.EdgeCount != 1 ||
+		first.CreatedAt.IsZero() {
+		t.Errorf("AddSnapshot() got = %+v", first)
+	}
+
+	second, err := s.AddSnapshot(graphID, "second")
+	if err != nil {
+		t.Fatalf("AddSnapshot() error = %v", err)
+	}
+
+	ss, err := s.Snapshots(graphID)
+	if err != nil {
+		t.Fatalf("Snapshots() error = %v", err)
+	}
+	if len(ss) != 2 || ss[0].ID != second.ID || ss[1].ID != first.ID {
+		t.Errorf("Snapshots() got = %+v, want newest first", ss)
+	}
+
+	_, err = s.AddSnapshot(graphID, "first")
+	if err != entity.ErrDuplicatedSnapshotName {
+		t.Errorf("AddSnapshot() error = %v, want %v", err,
+			entity.ErrDuplicatedSnapshotName)
+	}
+
+	// Names are unique only within the graph.
+	_, err = s.AddSnapshot(otherID, "first")
+	if err != nil {
+		t.Errorf("AddSnapshot() to other graph error = %v", err)
+	}
+
+	_, err = s.AddSnapshot(unknownID, "first")
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("AddSnapshot() error = %v, want %v", err,
+			entity.ErrGraphNotFound)
+	}
+
+	err = s.RemoveSnapshot(otherID, first.ID)
+	if err != entity.ErrSnapshotNotFound {
+		t.Errorf("RemoveSnapshot() of other graph error = %v, want %v", err,
+			entity.ErrSnapshotNotFound)
+	}
+
+	err = s.RemoveSnapshot(graphID, first.ID)
+	if err != nil {
+		t.Fatalf("RemoveSnapshot() error = %v", err)
+	}
+
+	err = s.RemoveSnapshot(graphID, first.ID)
+	if err != entity.ErrSnapshotNotFound {
+		t.Errorf("RemoveSnapshot() error = %v, want %v", err,
+			entity.ErrSnapshotNotFound)
+	}
+
+	ss, err = s.Snapshots(graphID)
+	if err != nil {
+		t.Fatalf("Snapshots() error = %v", err)
+	}
+	if len(ss) != 1 || ss[0].ID != second.ID {
+		t.Errorf("Snapshots() got = %+v, want only second", ss)
+	}
+}
+
+func testRestoreSnapshot(t *testing.T, s web.Storage) {
+	graphID := addGraph(t, s, "g")
+	otherID := addGraph(t, s, "other")
+	v1 := addVertex(t, s, graphID, 0, 0)
+	v2 := addVertex(t, s, graphID, 1, 0)
+	v3 := addVertex(t, s, graphID, 2, 0)
+	e := addEdge(t, s, graphID, v1, v2, 1)
+	addEdge(t, s, graphID, v2, v3, 1)
+
+	vs, es := vertexes(t, s, graphID), edges(t, s, graphID)
+
+	snap, err := s.AddSnapshot(graphID, "s")
+	if err != nil {
+		t.Fatalf("AddSnapshot() error = %v", err)
+	}
+
+	_, err = s.SetVertex(entity.Vertex{ID: v1, X: 10})
+	if err != nil {
+		t.Fatalf("SetVertex() error = %v", err)
+	}
+	err = s.RemoveVertex(v3)
+	if err != nil {
+		t.Fatalf("RemoveVertex() error = %v", err)
+	}
+	err = s.RemoveEdge(e)
+	if err != nil {
+		t.Fatalf("RemoveEdge() error = %v", err)
+	}
+	v4 := addVertex(t, s, graphID, 3, 0)
+	addEdge(t, s, graphID, v2, v4, 1)
+
+	err = s.RestoreSnapshot(graphID, snap.ID)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+
+	// Changed vertex gets new version, unchanged ones keep it.
+	vs[0].Version = 3
+
+	if got := vertexes(t, s, graphID); !reflect.DeepEqual(got, vs) {
+		t.Errorf("Vertexes() got = %+v after restore, want %+v", got, vs)
+	}
+	if got := edges(t, s, graphID); !reflect.DeepEqual(got, es) {
+		t.Errorf("Edges() got = %+v after restore, want %+v", got, es)
+	}
+
+	err = s.RestoreSnapshot(otherID, snap.ID)
+	if err != entity.ErrSnapshotNotFound {
+		t.Errorf("RestoreSnapshot() to other graph error = %v, want %v",
+			err, entity.ErrSnapshotNotFound)
+	}
+
+	err = s.RestoreSnapshot(graphID, unknownID)
+	if err != entity.ErrSnapshotNotFound {
+		t.Errorf("RestoreSnapshot() error = %v, want %v", err,
+			entity.ErrSnapshotNotFound)
+	}
+
+	err = s.RestoreSnapshot(unknownID, snap.ID)
+	if err != entity.ErrGraphNotFound {
+		t.Errorf("RestoreSnapshot() error = %v, want %v", err,
+			entity.ErrGraphNotFound)
+	}
+}
@@ -0,0 +1,305 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+)
+
+const maxBatchOperations = 10000
+
+var errInvalidOperation = errors.New("invalid operation")
+
+// batchOperation is a single operation of the batch. Vertex is set for
+// vertex operations and edge is set for edge operations. Negative vertex
+// and edge IDs are temporary IDs assigned by the client: IDs of added
+// vertexes and edges are mapped to real ones, so subsequent operations can
+// reference them.
+type batchOperation struct {
+	Type   string         `json:"type"`
+	Vertex *vertexRequest `json:"vertex"`
+	Edge   *edgeRequest   `json:"edge"`
+}
+
+type batchResult struct {
+	Vertexes map[int64]int64 `json:"vertexes"`
+	Edges    map[int64]int64 `json:"edges"`
+}
+
+// batch applies operations to the graph with graphID using storage s and
+// returns temporary IDs mapping and websocket messages describing the
+// changes.
+func batch(s Storage, graphID int64, ops []batchOperation) (batchResult,
+	[]echo.Map, error) {
+
+	res := batchResult{
+		Vertexes: map[int64]int64{},
+		Edges:    map[int64]int64{},
+	}
+
+	var msgs []echo.Map
+
+	resolve := func(ids map[int64]int64, id int64) (int64, error) {
+		if id >= 0 {
+			return id, nil
+		}
+		realID, exists := ids[id]
+		if !exists {
+			return 0, fmt.Errorf("%w: unknown temporary ID %d",
+				errInvalidOperation, id)
+		}
+		return realID, nil
+	}
+
+	vertex := func(id int64) (entity.Vertex, error) {
+		v, err := s.Vertex(id)
+		if err != nil {
+			return entity.Vertex{}, err
+		}
+		if v.GraphID != graphID {
+			return entity.Vertex{}, entity.ErrVertexNotFound
+		}
+		return v, nil
+	}
+
+	edge := func(id int64) (entity.Edge, error) {
+		e, err := s.Edge(id)
+		if err != nil {
+			return entity.Edge{}, err
+		}
+		if e.GraphID != graphID {
+			return entity.Edge{}, entity.ErrEdgeNotFound
+		}
+		return e, nil
+	}
+
+	apply := func(op batchOperation) error {
+		var err error
+
+		switch op.Type {
+		case "add-vertex", "update-vertex", "remove-vertex":
+			if op.Vertex == nil {
+				return fmt.Errorf("%w: %s without vertex",
+					errInvalidOperation, op.Type)
+			}
+			op.Vertex.GraphID = graphID
+		case "add-edge", "update-edge", "remove-edge":
+			if op.Edge == nil {
+				return fmt.Errorf("%w: %s without edge",
+					errInvalidOperation, op.Type)
+			}
+			op.Edge.GraphID = graphID
+		default:
+			return fmt.Errorf("%w: unknown type %q",
+				errInvalidOperation, op.Type)
+		}
+
+		switch op.Type {
+		case "add-vertex":
+			v := op.Vertex.vertex()
+			tempID := v.ID
+			v.ID, err = s.AddVertex(v)
+			if err != nil {
+				return fmt.Errorf("add vertex to storage: %w", err)
+			}
+			v.Version = 1
+			if tempID < 0 {
+				res.Vertexes[tempID] = v.ID
+			}
+			msgs = append(msgs, echo.Map{"type": "new-vertex", "data": v})
+
+		case "update-vertex":
+			op.Vertex.ID, err = resolve(res.Vertexes, op.Vertex.ID)
+			if err != nil {
+				return err
+			}
+			if op.Vertex.Version == 0 {
+				return fmt.Errorf("%w: version is required",
+					errInvalidOperation)
+			}
+			v, err := op.Vertex.update(s)
+			if err != nil {
+				return err
+			}
+			if v.GraphID != graphID {
+				return entity.ErrVertexNotFound
+			}
+			v.Version, err = s.SetVertex(v)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, echo.Map{"type": "vertex-update", "data": v})
+
+		case "remove-vertex":
+			id, err := resolve(res.Vertexes, op.Vertex.ID)
+			if err != nil {
+				return err
+			}
+			v, err := vertex(id)
+			if err != nil {
+				return err
+			}
+			err = s.RemoveVertex(id)
+			if err != nil {
+				return fmt.Errorf("remove vertex from storage: %w", err)
+			}
+			msgs = append(msgs, echo.Map{"type": "vertex-removed", "data": v})
+
+		case "add-edge":
+			op.Edge.From, err = resolve(res.Vertexes, op.Edge.From)
+			if err != nil {
+				return err
+			}
+			op.Edge.To, err = resolve(res.Vertexes, op.Edge.To)
+			if err != nil {
+				return err
+			}
+			// Ends must belong to the graph.
+			for _, id := range []int64{op.Edge.From, op.Edge.To} {
+				_, err = vertex(id)
+				if err != nil {
+					return err
+				}
+			}
+			e, err := op.Edge.add(s)
+			if err != nil {
+				return err
+			}
+			tempID := e.ID
+			e.ID, err = s.AddEdge(e)
+			if err != nil {
+				return fmt.Errorf("add edge to storage: %w", err)
+			}
+			e.Version = 1
+			if tempID < 0 {
+				res.Edges[tempID] = e.ID
+			}
+			msgs = append(msgs, echo.Map{"type": "new-edge", "data": e})
+
+		case "update-edge":
+			op.Edge.ID, err = resolve(res.Edges, op.Edge.ID)
+			if err != nil {
+				return err
+			}
+			if op.Edge.Version == 0 {
+				return fmt.Errorf("%w: version is required",
+					errInvalidOperation)
+			}
+			e, err := op.Edge.update(s)
+			if err != nil {
+				return err
+			}
+			if e.GraphID != graphID {
+				return entity.ErrEdgeNotFound
+			}
+			e.Version, err = s.SetEdge(e)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, echo.Map{"type": "edge-update", "data": e})
+
+		case "remove-edge":
+			id, err := resolve(res.Edges, op.Edge.ID)
+			if err != nil {
+				return err
+			}
+			e, err := edge(id)
+			if err != nil {
+				return err
+			}
+			err = s.RemoveEdge(id)
+			if err != nil {
+				return fmt.Errorf("remove edge from storage: %w", err)
+			}
+			msgs = append(msgs, echo.Map{"type": "edge-removed", "data": e})
+		}
+
+		return nil
+	}
+
+	for i, op := range ops {
+		err := apply(op)
+		if err != nil {
+			return res, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return res, msgs, nil
+}
+
+func (s *Server) postAPIGraphBatch(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+
+	err = c.Bind(&req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"bind batch: "+err.Error())
+	}
+
+	if len(req.Operations) > maxBatchOperations {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("batch has more than %d operations",
+				maxBatchOperations))
+	}
+
+	var (
+		res  batchResult
+		data []byte
+	)
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		_, err := st.Graph(graphID)
+		if err != nil {
+			return err
+		}
+
+		var msgs []echo.Map
+
+		res, msgs, err = batch(st, graphID, req.Operations)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		data, err = s.publish(st, graphID, echo.Map{
+			"type": "batch",
+			"data": msgs,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrGraphNotFound) ||
+			errors.Is(err, entity.ErrVertexNotFound) ||
+			errors.Is(err, entity.ErrEdgeNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				"apply batch: "+err.Error())
+		}
+		if errors.Is(err, entity.ErrVersionConflict) {
+			return echo.NewHTTPError(http.StatusConflict,
+				"apply batch: "+err.Error())
+		}
+		if errors.Is(err, errInvalidOperation) {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"apply batch: "+err.Error())
+		}
+		return fmt.Errorf("apply batch: %w", err)
+	}
+
+	if data != nil {
+		s.send(graphID, "postAPIGraphBatch", data)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
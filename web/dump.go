@@ -0,0 +1,195 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/graphio"
+	"github.com/labstack/echo"
+)
+
+// mimeNDJSON is the content type of the instance dump.
+const mimeNDJSON = "application/x-ndjson"
+
+// loadGraph returns the graph with all its vertexes and edges.
+func loadGraph(st Storage, graphID int64) (g graphio.Graph, err error) {
+	g.Graph, err = st.Graph(graphID)
+	if err != nil {
+		return g, fmt.Errorf("get graph from storage: %w", err)
+	}
+
+	g.Vertexes, err = st.Vertexes(graphID)
+	if err != nil {
+		return g, fmt.Errorf("get vertexes from storage: %w", err)
+	}
+
+	g.Edges, err = st.Edges(graphID)
+	if err != nil {
+		return g, fmt.Errorf("get edges from storage: %w", err)
+	}
+
+	return g, nil
+}
+
+// loadGraphInTx is loadGraph which reads the graph in one read
+// transaction.
+func loadGraphInTx(st Storage, graphID int64) (g graphio.Graph, err error) {
+	err = st.InReadTx(func(st Storage) (err error) {
+		g, err = loadGraph(st, graphID)
+		return
+	})
+	return
+}
+
+func (s *Server) getAPIGraphDump(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	g, err := loadGraphInTx(s.storage, graphID)
+	if err != nil {
+		if errors.Is(err, entity.ErrGraphNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				entity.ErrGraphNotFound)
+		}
+		return err
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType,
+		echo.MIMEApplicationJSONCharsetUTF8)
+	res.WriteHeader(http.StatusOK)
+
+	err = graphio.WriteDump(res, g)
+	if err != nil {
+		return fmt.Errorf("write dump: %w", err)
+	}
+
+	return nil
+}
+
+// getAPIGraphsDump streams dumps of all graphs one per line. Every graph
+// is read in its own transaction, so graphs removed while dumping are
+// skipped.
+func (s *Server) getAPIGraphsDump(c echo.Context) error {
+	gs, err := s.storage.Graphs()
+	if err != nil {
+		return fmt.Errorf("get graphs from storage: %w", err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	for _, g := range gs {
+		dg, err := loadGraphInTx(s.storage, g.ID)
+		if err != nil {
+			if errors.Is(err, entity.ErrGraphNotFound) {
+				continue
+			}
+			return err
+		}
+
+		err = graphio.WriteDump(res, dg)
+		if err != nil {
+			return fmt.Errorf("write dump: %w", err)
+		}
+
+		res.Flush()
+	}
+
+	return nil
+}
+
+// restoredGraph describes the graph recreated from the dump.
+type restoredGraph struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Vertexes int    `json:"vertexes"`
+	Edges    int    `json:"edges"`
+}
+
+// postAPIGraphsRestore recreates graphs from the single dump or from the
+// instance dump in one transaction. Graphs, vertexes and edges get new IDs.
+// Dumps are read and checked before the transaction, so it is not held
+// while the client uploads them.
+func (s *Server) postAPIGraphsRestore(c echo.Context) error {
+	name := c.QueryParam("name")
+
+	r, err := readImportFile(c)
+	if err != nil {
+		return err
+	}
+
+	var gs []graphio.Graph
+
+	dr := graphio.NewDumpReader(r)
+	for {
+		g, err := dr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"read dump: "+err.Error())
+		}
+
+		if name != "" {
+			if len(gs) > 0 {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					"name is allowed only for single graph")
+			}
+			g.Graph.Name = name
+		}
+
+		if g.Graph.Name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"empty name")
+		}
+
+		gs = append(gs, g)
+	}
+
+	if len(gs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"no graphs in dump")
+	}
+
+	rgs := make([]restoredGraph, 0, len(gs))
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		for _, g := range gs {
+			graphID, err := importGraph(st, g)
+			if err != nil {
+				if err == entity.ErrDuplicatedGraphName {
+					return echo.NewHTTPError(http.StatusConflict,
+						fmt.Sprintf("%s: %s", err, g.Graph.Name))
+				}
+				return fmt.Errorf("restore graph %q: %w", g.Graph.Name,
+					err)
+			}
+
+			rgs = append(rgs, restoredGraph{
+				ID:       graphID,
+				Name:     g.Graph.Name,
+				Vertexes: len(g.Vertexes),
+				Edges:    len(g.Edges),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"graphs": rgs,
+	})
+}
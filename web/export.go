@@ -0,0 +1,144 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/dimuls/graph/dijkstra"
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/graphio"
+	"github.com/labstack/echo"
+)
+
+func (s *Server) getAPIGraphExport(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	format := c.QueryParam("format")
+	switch format {
+	case "dot", "svg", "csv", "jgf", "gexf":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"unknown format")
+	}
+
+	g, err := loadGraph(s.storage, graphID)
+	if err != nil {
+		if errors.Is(err, entity.ErrGraphNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound,
+				entity.ErrGraphNotFound)
+		}
+		return err
+	}
+
+	res := c.Response()
+
+	switch format {
+	case "dot":
+		res.Header().Set(echo.HeaderContentType, "text/vnd.graphviz")
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteDOT(res, g)
+		if err != nil {
+			return fmt.Errorf("write DOT: %w", err)
+		}
+		return nil
+	case "csv":
+		return exportCSV(c, g)
+	case "jgf":
+		res.Header().Set(echo.HeaderContentType,
+			echo.MIMEApplicationJSONCharsetUTF8)
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteJGF(res, g)
+		if err != nil {
+			return fmt.Errorf("write JGF: %w", err)
+		}
+		return nil
+	case "gexf":
+		res.Header().Set(echo.HeaderContentType, "application/gexf+xml")
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteGEXF(res, g)
+		if err != nil {
+			return fmt.Errorf("write GEXF: %w", err)
+		}
+		return nil
+	}
+
+	var highlight []int64
+
+	fromStr, toStr := c.QueryParam("from"), c.QueryParam("to")
+	if fromStr != "" || toStr != "" {
+		from, err := strconv.ParseInt(fromStr, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"failed to parse from: "+err.Error())
+		}
+
+		to, err := strconv.ParseInt(toStr, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"failed to parse to: "+err.Error())
+		}
+
+		path, err := dijkstra.ShortestPath(g.Vertexes, g.Edges, from, to)
+		if err != nil {
+			var cycleErr *dijkstra.NegativeCycleError
+			if errors.As(err, &cycleErr) {
+				return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+					"error":          err.Error(),
+					"negative_cycle": cycleErr.EdgeIDs,
+				})
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		highlight = path.EdgeIDs
+	}
+
+	res.Header().Set(echo.HeaderContentType, "image/svg+xml")
+	res.WriteHeader(http.StatusOK)
+	err = graphio.WriteSVG(res, g, highlight)
+	if err != nil {
+		return fmt.Errorf("write SVG: %w", err)
+	}
+	return nil
+}
+
+// exportCSV writes the zip archive with vertices and edges CSV files or
+// one of them selected by file query parameter.
+func exportCSV(c echo.Context, g graphio.Graph) error {
+	res := c.Response()
+
+	var err error
+
+	switch c.QueryParam("file") {
+	case "":
+		res.Header().Set(echo.HeaderContentType, "application/zip")
+		res.Header().Set(echo.HeaderContentDisposition,
+			`attachment; filename="graph.zip"`)
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteCSVZip(res, g)
+	case "vertices":
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteCSV(res, io.Discard, g)
+	case "edges":
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+		res.WriteHeader(http.StatusOK)
+		err = graphio.WriteCSV(io.Discard, res, g)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"unknown file")
+	}
+	if err != nil {
+		return fmt.Errorf("write CSV: %w", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package web
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -50,6 +53,10 @@ const indexPage = `<!DOCTYPE html>
 			</div>
 			<div class="new-graph">
 				<input placeholder="new graph name" data-bind="textInput: newGraphName"/>
+				<label>
+					<input type="checkbox" data-bind="checked: newGraphDirected"/>
+					directed
+				</label>
 				<button data-bind="click: addGraph">add</button>
 			</div>
 		</div>
@@ -76,6 +83,7 @@ const indexPage = `<!DOCTYPE html>
 		        	    })
 		        	},
 		        	newGraphName: ko.observable(''),
+		        	newGraphDirected: ko.observable(true),
 		        	addGraph: function() {
 		        	    $.ajax({
 		        	    	url: '/api/graphs',
@@ -83,11 +91,13 @@ const indexPage = `<!DOCTYPE html>
 		        	    	contentType: 'application/json',
 		        	    	data: JSON.stringify({
 		        	    		name: app.newGraphName(),
+		        	    		directed: app.newGraphDirected(),
 		        	    	}),
 		        	    	success: function(id) {
 		        	    	    app.graphs.push({
 		        	    	    	id: id,
 		        	    	    	name: app.newGraphName(),
+		        	    	    	directed: app.newGraphDirected(),
 		        	    	    });
 		        	    	    app.newGraphName('')
 		        	     	}
@@ -135,25 +145,50 @@ const graphPage = `<!DOCTYPE>
 		    var graph;
 		    var data;
 		    
+		    function edgeLabel(e) {
+		        if (e.label) {
+		            return e.label+' ('+e.weight.toString()+')';
+		        }
+		        return e.weight.toString();
+		    }
+		    
+		    function toNode(v) {
+		        return {
+		            id: v.id,
+		            x: v.x,
+		            y: v.y,
+		            label: v.label,
+		            version: v.version,
+		            physics: false
+		        };
+		    }
+		    
+		    function toEdge(e) {
+		        return {
+		            id: e.id,
+		            from: e.from,
+		            to: e.to,
+		            label: edgeLabel(e),
+		            arrows: e.directed ? 'to' : '',
+		            version: e.version
+		        };
+		    }
+		    
+		    // reconcile replaces stale item with the current state sent by
+		    // server on version conflict.
+		    function reconcile(dataSet, toItem) {
+		        return function(xhr) {
+		            if (xhr.status === 409 && xhr.responseJSON) {
+		                dataSet.update(toItem(xhr.responseJSON.current));
+		                alert('it was changed by someone else, try again');
+		            }
+		        };
+		    }
+		    
 		    function initGraph(graph) {
-		        var nodes = new vis.DataSet(graph.vertexes.map(function(v) {
-		            return {
-		                id: v.id,
-		                x: v.x,
-		                y: v.y,
-		                physics: false
-		            };
-		        }));
+		        var nodes = new vis.DataSet(graph.vertexes.map(toNode));
 		        
-		        var edges = new vis.DataSet(graph.edges.map(function(e) {
-		            return {
-		                id: e.id,
-		                from: e.from,
-		                to: e.to,
-		                label: e.weight.toString(),
-		                arrows: 'to',
-		            };
-		        }));
+		        var edges = new vis.DataSet(graph.edges.map(toEdge));
 		        
 		        var container = document.getElementById('graph');
 		        
@@ -242,9 +277,11 @@ const graphPage = `<!DOCTYPE>
 								id: node.id,
 								graph_id: graphID,
 								x: positions[node.id].x,
-								y: positions[node.id].y							
-							})
-						});		                
+								y: positions[node.id].y,
+								version: node.version
+							}),
+							error: reconcile(nodes, toNode)
+						});
 		            }
 		        });
 		        
@@ -257,15 +294,43 @@ const graphPage = `<!DOCTYPE>
 							alert('invalid weight: '+weightStr);
 							return
 						}
+		                var update = {
+		                    id: edge.id,
+		                    graph_id: graphID,
+		                    weight: weight,
+		                    version: edge.version
+		                };
+		                var label = prompt('enter edge label');
+		                if (label !== null) {
+		                    update.label = label;
+		                }
 		            	$.ajax({
 							url: '/api/edges',
 							type: 'PUT',
 							contentType: 'application/json',
+							data: JSON.stringify(update),
+							error: reconcile(edges, toEdge)
+						});
+					} else if (params.nodes.length === 1) {
+		                var nodeID = params.nodes[0];
+		                var label = prompt('enter vertex label');
+		                if (label === null) {
+		                    return
+		                }
+		                var positions = graph.getPositions([nodeID]);
+		                $.ajax({
+							url: '/api/vertexes',
+							type: 'PUT',
+							contentType: 'application/json',
 							data: JSON.stringify({
-								id: edge.id,
+								id: nodeID,
 								graph_id: graphID,
-								weight: weight							
-							})
+								x: positions[nodeID].x,
+								y: positions[nodeID].y,
+								label: label,
+								version: nodes.get(nodeID).version
+							}),
+							error: reconcile(nodes, toNode)
 						});
 					}
 		        });
@@ -293,55 +358,62 @@ const graphPage = `<!DOCTYPE>
 		            	data: { from: from, to: to },
 		            	success: function(edges) {
 		            		graph.selectEdges(edges);
+		            	},
+		            	error: function(xhr) {
+		            	    var res = xhr.responseJSON;
+		            	    if (res && res.negative_cycle) {
+		            	        graph.selectEdges(res.negative_cycle);
+		            	        alert('negative cycle found');
+		            	    }
 		            	}
 		            });
 		        });
 		    }
 		    
+		    // Ctrl+Z undoes the last change of the graph and Ctrl+Shift+Z
+		    // redoes it, results come through the websocket.
+		    $(document).on('keydown', function(e) {
+		        if (!(e.ctrlKey || e.metaKey) || e.key.toLowerCase() !== 'z') {
+		            return
+		        }
+		        if ($(e.target).is('input, textarea')) {
+		            return
+		        }
+		        e.preventDefault();
+		        $.ajax({
+		            url: '/api/graphs/'+graphID+(e.shiftKey ? '/redo' : '/undo'),
+		            type: 'POST'
+		        });
+		    });
+		    
 		    function connect() {
 				var ws = new WebSocket('ws://'+location.host+'/api/graphs/'+graphID);
 				
-				ws.onmessage = function(e) {
-					var msg = JSON.parse(e.data);
+				function handleMessage(msg) {
 					switch (msg.type) {
 					 	case 'set-graph':
 					 	    initGraph(msg.data);
 					    	break;
+					    case 'batch':
+					        msg.data.forEach(handleMessage);
+					        break;
 					    case 'graph-removed':
 					        window.location.href = "/";
 					        break;
 						case 'new-vertex':
-						    data.nodes.add([{
-								id: msg.data.id,
-								x: msg.data.x,
-								y: msg.data.y,
-								physics: false
-							}]);
+						    data.nodes.add([toNode(msg.data)]);
 						    break;
 						case 'vertex-update':
-						    data.nodes.update({
-						    	id: msg.data.id,
-						    	x: msg.data.x,
-								y: msg.data.y
-						    });
+						    data.nodes.update(toNode(msg.data));
 						    break;
 						case 'vertex-removed':
 						    data.nodes.remove(msg.data.id);
 						    break;
 						case 'new-edge':
-						    data.edges.add([{
-								id: msg.data.id,
-								from: msg.data.from,
-								to: msg.data.to,
-								label: msg.data.weight.toString(),
-								arrows: 'to',
-							}]);
+						    data.edges.add([toEdge(msg.data)]);
 						    break;
 						case 'edge-update':
-						    data.edges.update({
-						    	id: msg.data.id,
-						    	label: msg.data.weight.toString()
-						    });
+						    data.edges.update(toEdge(msg.data));
 						    break;
 						case 'edge-removed':
 						    data.edges.remove(msg.data.id);
@@ -350,6 +422,10 @@ const graphPage = `<!DOCTYPE>
 					    	console.warn('unknown message: ', msg);
 					    	break;
 					}
+				}
+				
+				ws.onmessage = function(e) {
+					handleMessage(JSON.parse(e.data));
 				};
 				
 				ws.onclose = function(e) {
@@ -390,20 +466,26 @@ func (s *Server) getAPIGraphs(c echo.Context) error {
 }
 
 func (s *Server) postAPIGraphs(c echo.Context) error {
-	var g entity.Graph
+	var req struct {
+		entity.Graph
+		Directed *bool `json:"directed"`
+	}
 
-	err := c.Bind(&g)
+	err := c.Bind(&req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"bind graph: "+err.Error())
 	}
 
+	g := req.Graph
+	g.Directed = req.Directed == nil || *req.Directed
+
 	if g.Name == "" {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"empty name")
 	}
 
-	id, err := s.storage.AddGraph(g)
+	id, err := s.actorStorage(c).AddGraph(g)
 	if err != nil {
 		if err == entity.ErrDuplicatedGraphName {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -414,6 +496,43 @@ func (s *Server) postAPIGraphs(c echo.Context) error {
 	return c.JSON(http.StatusCreated, id)
 }
 
+func (s *Server) postAPIGraphClone(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	err = c.Bind(&req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"bind graph: "+err.Error())
+	}
+
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"empty name")
+	}
+
+	id, err := s.actorStorage(c).CloneGraph(graphID, req.Name)
+	if err != nil {
+		switch err {
+		case entity.ErrGraphNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		case entity.ErrDuplicatedGraphName:
+			return echo.NewHTTPError(http.StatusConflict, err)
+		}
+		return fmt.Errorf("clone graph in storage: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, id)
+}
+
 func (s *Server) getAPIGraph(c echo.Context) error {
 	graphID, err := strconv.ParseInt(c.Param("graph_id"),
 		10, 64)
@@ -510,94 +629,121 @@ func (s *Server) deleteAPIGraph(c echo.Context) error {
 			"invalid graph_id")
 	}
 
-	err = s.storage.RemoveGraph(graphID)
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		err := st.RemoveGraph(graphID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.publish(st, graphID, graphRemovedMessage)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("remove graph from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[graphID]; exists {
-		for ws, closeWS := range listeners {
-			websocket.JSON.Send(ws, echo.Map{
-				"type": "graph-removed",
-			})
-			close(closeWS)
-		}
-		delete(s.graphListeners, graphID)
-	}
-	s.graphListenersMx.RUnlock()
+	s.removeGraph(graphID, "deleteAPIGraph")
 
 	return c.NoContent(http.StatusNoContent)
 }
 
 func (s *Server) postAPIVertexes(c echo.Context) error {
-	var v entity.Vertex
+	var req vertexRequest
 
-	err := c.Bind(&v)
+	err := c.Bind(&req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"bind vertex: "+err.Error())
 	}
 
-	id, err := s.storage.AddVertex(v)
-	if err != nil {
-		return fmt.Errorf("add vertex to storage: %w", err)
-	}
+	v := req.vertex()
+
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		id, err := st.AddVertex(v)
+		if err != nil {
+			return err
+		}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[v.GraphID]; exists {
 		v.ID = id
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "new-vertex",
-				"data": v,
-			})
-			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "postAPIVertexes").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
-			}
+		v.Version = 1
+
+		data, err = s.publish(st, v.GraphID, echo.Map{
+			"type": "new-vertex",
+			"data": v,
+		})
+		return err
+	})
+	if err != nil {
+		if err == entity.ErrGraphNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
 		}
+		return fmt.Errorf("add vertex to storage: %w", err)
 	}
-	s.graphListenersMx.RUnlock()
+
+	s.send(v.GraphID, "postAPIVertexes", data)
 
 	return c.NoContent(http.StatusCreated)
 }
 
 func (s *Server) putAPIVertexes(c echo.Context) error {
-	var v entity.Vertex
+	var req vertexRequest
 
-	err := c.Bind(&v)
+	err := c.Bind(&req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"bind vertex: "+err.Error())
 	}
 
-	err = s.storage.SetVertex(v)
+	v, err := req.update(s.storage)
 	if err != nil {
 		if err == entity.ErrVertexNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, err)
 		}
-		return fmt.Errorf("set vertex in storage: %w", err)
+		return fmt.Errorf("get vertex from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[v.GraphID]; exists {
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "vertex-update",
-				"data": v,
-			})
+	v.Version, err = requestVersion(c, v.Version)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) (err error) {
+		v.Version, err = st.SetVertex(v)
+		if err != nil {
+			return err
+		}
+
+		data, err = s.publish(st, v.GraphID, echo.Map{
+			"type": "vertex-update",
+			"data": v,
+		})
+		return err
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrVertexNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		case entity.ErrVersionConflict:
+			current, err := s.storage.Vertex(v.ID)
 			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "putAPIVertexes").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
+				return fmt.Errorf("get vertex from storage: %w", err)
 			}
+			return c.JSON(http.StatusConflict, echo.Map{
+				"error":   entity.ErrVersionConflict.Error(),
+				"current": current,
+			})
 		}
+		return fmt.Errorf("set vertex in storage: %w", err)
 	}
-	s.graphListenersMx.RUnlock()
+
+	s.send(v.GraphID, "putAPIVertexes", data)
+
+	c.Response().Header().Set("ETag",
+		strconv.Quote(strconv.FormatInt(v.Version, 10)))
 
 	return c.NoContent(http.StatusNoContent)
 }
@@ -618,99 +764,133 @@ func (s *Server) deleteAPIVertex(c echo.Context) error {
 		return fmt.Errorf("get vertex from storage: %w", err)
 	}
 
-	err = s.storage.RemoveVertex(vertexID)
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		err := st.RemoveVertex(vertexID)
+		if err != nil {
+			return err
+		}
+
+		data, err = s.publish(st, v.GraphID, echo.Map{
+			"type": "vertex-removed",
+			"data": v,
+		})
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("remove vertex from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[v.GraphID]; exists {
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "vertex-removed",
-				"data": v,
-			})
-			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "deleteAPIVertex").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
-			}
-		}
-	}
-	s.graphListenersMx.RUnlock()
+	s.send(v.GraphID, "deleteAPIVertex", data)
 
 	return c.NoContent(http.StatusNoContent)
 }
 
 func (s *Server) postAPIEdges(c echo.Context) error {
-	var e entity.Edge
+	var req edgeRequest
 
-	err := c.Bind(&e)
+	err := c.Bind(&req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"bind edge: "+err.Error())
 	}
 
-	id, err := s.storage.AddEdge(e)
+	e, err := req.add(s.storage)
 	if err != nil {
-		return fmt.Errorf("add edge to storage: %w", err)
+		if err == entity.ErrGraphNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return fmt.Errorf("get graph from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[e.GraphID]; exists {
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		id, err := st.AddEdge(e)
+		if err != nil {
+			return err
+		}
+
 		e.ID = id
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "new-edge",
-				"data": e,
-			})
-			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "postAPIEdges").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
-			}
+		e.Version = 1
+
+		data, err = s.publish(st, e.GraphID, echo.Map{
+			"type": "new-edge",
+			"data": e,
+		})
+		return err
+	})
+	if err != nil {
+		if err == entity.ErrGraphNotFound ||
+			err == entity.ErrVertexNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
 		}
+		return fmt.Errorf("add edge to storage: %w", err)
 	}
-	s.graphListenersMx.RUnlock()
+
+	s.send(e.GraphID, "postAPIEdges", data)
 
 	return c.NoContent(http.StatusCreated)
 }
 
 func (s *Server) putAPIEdges(c echo.Context) error {
-	var e entity.Edge
+	var req edgeRequest
 
-	err := c.Bind(&e)
+	err := c.Bind(&req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			"bind edge: "+err.Error())
 	}
 
-	err = s.storage.SetEdge(e)
+	e, err := req.update(s.storage)
 	if err != nil {
 		if err == entity.ErrEdgeNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, err)
 		}
-		return fmt.Errorf("set edge in storage: %w", err)
+		return fmt.Errorf("get edge from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[e.GraphID]; exists {
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "edge-update",
-				"data": e,
-			})
+	e.Version, err = requestVersion(c, e.Version)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) (err error) {
+		e.Version, err = st.SetEdge(e)
+		if err != nil {
+			return err
+		}
+
+		data, err = s.publish(st, e.GraphID, echo.Map{
+			"type": "edge-update",
+			"data": e,
+		})
+		return err
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrEdgeNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		case entity.ErrVersionConflict:
+			current, err := s.storage.Edge(e.ID)
 			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "putAPIEdges").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
+				return fmt.Errorf("get edge from storage: %w", err)
 			}
+			return c.JSON(http.StatusConflict, echo.Map{
+				"error":   entity.ErrVersionConflict.Error(),
+				"current": current,
+			})
 		}
+		return fmt.Errorf("set edge in storage: %w", err)
 	}
-	s.graphListenersMx.RUnlock()
+
+	s.send(e.GraphID, "putAPIEdges", data)
+
+	c.Response().Header().Set("ETag",
+		strconv.Quote(strconv.FormatInt(e.Version, 10)))
 
 	return c.NoContent(http.StatusNoContent)
 }
@@ -731,32 +911,105 @@ func (s *Server) deleteAPIEdge(c echo.Context) error {
 		return fmt.Errorf("get edge from storage: %v", err)
 	}
 
-	err = s.storage.RemoveEdge(edgeID)
+	var data []byte
+
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		err := st.RemoveEdge(edgeID)
+		if err != nil {
+			return err
+		}
+
+		data, err = s.publish(st, e.GraphID, echo.Map{
+			"type": "edge-removed",
+			"data": e,
+		})
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("remove edge from storage: %w", err)
 	}
 
-	s.graphListenersMx.RLock()
-	if listeners, exists := s.graphListeners[e.GraphID]; exists {
-		for ws, closeWS := range listeners {
-			err = websocket.JSON.Send(ws, echo.Map{
-				"type": "edge-removed",
-				"data": e,
-			})
+	s.send(e.GraphID, "deleteAPIEdge", data)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) getAPIGraphShortestPath(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	from, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"failed to parse from: "+err.Error())
+	}
+
+	to, err := strconv.ParseInt(c.QueryParam("to"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"failed to parse to: "+err.Error())
+	}
+
+	vs, err := s.storage.Vertexes(graphID)
+	if err != nil {
+		return fmt.Errorf("get vertexes from storage: %w", err)
+	}
+
+	es, err := s.storage.Edges(graphID)
+	if err != nil {
+		return fmt.Errorf("get edges from storage: %w", err)
+	}
+
+	format := c.QueryParam("format")
+	if format != "" && format != "edge_ids" && format != "path" {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"unknown format")
+	}
+
+	var path dijkstra.Path
+
+	switch c.QueryParam("algorithm") {
+	case "", "dijkstra":
+		path, err = dijkstra.ShortestPath(vs, es, from, to)
+	case "astar":
+		scale := 1.0
+		if scaleStr := c.QueryParam("scale"); scaleStr != "" {
+			scale, err = strconv.ParseFloat(scaleStr, 64)
 			if err != nil {
-				s.log.WithError(err).
-					WithField("method", "deleteAPIEdge").
-					Error("failed to send JSON to websocket")
-				close(closeWS)
+				return echo.NewHTTPError(http.StatusBadRequest,
+					"failed to parse scale: "+err.Error())
 			}
 		}
+		path, err = dijkstra.AStarShortestPath(vs, es, from, to, scale)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"unknown algorithm")
+	}
+	if err != nil {
+		var cycleErr *dijkstra.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"error":          err.Error(),
+				"negative_cycle": cycleErr.EdgeIDs,
+			})
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
-	s.graphListenersMx.RUnlock()
 
-	return c.NoContent(http.StatusNoContent)
+	if format == "path" {
+		return c.JSON(http.StatusOK, path)
+	}
+
+	return c.JSON(http.StatusOK, path.EdgeIDs)
 }
 
-func (s *Server) getAPIGraphShortestPath(c echo.Context) error {
+const maxK = 100
+
+func (s *Server) getAPIGraphKShortestPaths(c echo.Context) error {
 	graphID, err := strconv.ParseInt(c.Param("graph_id"),
 		10, 64)
 	if err != nil {
@@ -776,6 +1029,17 @@ func (s *Server) getAPIGraphShortestPath(c echo.Context) error {
 			"failed to parse to: "+err.Error())
 	}
 
+	k, err := strconv.Atoi(c.QueryParam("k"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"failed to parse k: "+err.Error())
+	}
+
+	if k > maxK {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("k must not exceed %d", maxK))
+	}
+
 	vs, err := s.storage.Vertexes(graphID)
 	if err != nil {
 		return fmt.Errorf("get vertexes from storage: %w", err)
@@ -786,10 +1050,179 @@ func (s *Server) getAPIGraphShortestPath(c echo.Context) error {
 		return fmt.Errorf("get edges from storage: %w", err)
 	}
 
-	path, err := dijkstra.ShortestPath(vs, es, from, to)
+	paths, err := dijkstra.KShortestPaths(vs, es, from, to, k)
 	if err != nil {
+		var cycleErr *dijkstra.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"error":          err.Error(),
+				"negative_cycle": cycleErr.EdgeIDs,
+			})
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
-	return c.JSON(http.StatusOK, path)
+	return c.JSON(http.StatusOK, paths)
+}
+
+func (s *Server) getAPIGraphDistances(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	from, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"failed to parse from: "+err.Error())
+	}
+
+	vs, err := s.storage.Vertexes(graphID)
+	if err != nil {
+		return fmt.Errorf("get vertexes from storage: %w", err)
+	}
+
+	es, err := s.storage.Edges(graphID)
+	if err != nil {
+		return fmt.Errorf("get edges from storage: %w", err)
+	}
+
+	tree, err := dijkstra.ShortestPathTree(vs, es, from)
+	if err != nil {
+		var cycleErr *dijkstra.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"error":          err.Error(),
+				"negative_cycle": cycleErr.EdgeIDs,
+			})
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	distances := make([]echo.Map, 0, len(vs))
+
+	for _, v := range vs {
+		d, reachable := tree.Distances[v.ID]
+		if !reachable {
+			distances = append(distances, echo.Map{
+				"vertex_id":    v.ID,
+				"reachable":    false,
+				"distance":     nil,
+				"prev_edge_id": nil,
+			})
+			continue
+		}
+
+		var prevEdgeID interface{}
+		if id, exists := tree.PrevEdgeIDs[v.ID]; exists {
+			prevEdgeID = id
+		}
+
+		distances = append(distances, echo.Map{
+			"vertex_id":    v.ID,
+			"reachable":    true,
+			"distance":     d,
+			"prev_edge_id": prevEdgeID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, distances)
+}
+
+const maxDistanceMatrixVertexes = 1000
+
+func (s *Server) getAPIGraphDistanceMatrix(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "csv" {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"unknown format")
+	}
+
+	vs, err := s.storage.Vertexes(graphID)
+	if err != nil {
+		return fmt.Errorf("get vertexes from storage: %w", err)
+	}
+
+	if len(vs) > maxDistanceMatrixVertexes {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("graph has more than %d vertexes",
+				maxDistanceMatrixVertexes))
+	}
+
+	es, err := s.storage.Edges(graphID)
+	if err != nil {
+		return fmt.Errorf("get edges from storage: %w", err)
+	}
+
+	m, err := dijkstra.DistanceMatrix(vs, es)
+	if err != nil {
+		var cycleErr *dijkstra.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"error":          err.Error(),
+				"negative_cycle": cycleErr.EdgeIDs,
+			})
+		}
+		return fmt.Errorf("compute distance matrix: %w", err)
+	}
+
+	if format == "csv" {
+		c.Response().Header().Set(echo.HeaderContentType,
+			"text/csv; charset=UTF-8")
+		c.Response().WriteHeader(http.StatusOK)
+
+		w := csv.NewWriter(c.Response())
+
+		row := make([]string, len(m.VertexIDs)+1)
+		for i, id := range m.VertexIDs {
+			row[i+1] = strconv.FormatInt(id, 10)
+		}
+		err = w.Write(row)
+		if err != nil {
+			return fmt.Errorf("write CSV: %w", err)
+		}
+
+		for i, id := range m.VertexIDs {
+			row[0] = strconv.FormatInt(id, 10)
+			for j, d := range m.Distances[i] {
+				if math.IsInf(d, 1) {
+					row[j+1] = ""
+				} else {
+					row[j+1] = strconv.FormatFloat(d, 'g', -1, 64)
+				}
+			}
+			err = w.Write(row)
+			if err != nil {
+				return fmt.Errorf("write CSV: %w", err)
+			}
+		}
+
+		w.Flush()
+
+		return w.Error()
+	}
+
+	distances := make([][]interface{}, len(m.Distances))
+	for i := range m.Distances {
+		distances[i] = make([]interface{}, len(m.Distances[i]))
+		for j, d := range m.Distances[i] {
+			if !math.IsInf(d, 1) {
+				distances[i][j] = d
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"vertex_ids": m.VertexIDs,
+		"distances":  distances,
+	})
 }
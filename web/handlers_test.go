@@ -0,0 +1,269 @@
+package web_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/memory"
+	"github.com/dimuls/graph/web"
+	"github.com/labstack/echo"
+)
+
+// do sends the request with JSON body to the server and returns the
+// response.
+func do(t *testing.T, h http.Handler, method, path, body string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// addGraph adds the graph with the given name through the API and returns
+// its ID.
+func addGraph(t *testing.T, h http.Handler, name string) int64 {
+	t.Helper()
+
+	rec := do(t, h, http.MethodPost, "/api/graphs",
+		fmt.Sprintf(`{"name": %q}`, name))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/graphs status = %d, want %d: %s", rec.Code,
+			http.StatusCreated, rec.Body)
+	}
+
+	var id int64
+	err := json.Unmarshal(rec.Body.Bytes(), &id)
+	if err != nil {
+		t.Fatalf("POST /api/graphs body = %s: %v", rec.Body, err)
+	}
+
+	return id
+}
+
+func newTestServer() (*memory.Storage, *web.Server) {
+	s := memory.NewStorage()
+	return s, web.NewServer("", s)
+}
+
+func TestServer_graphs(t *testing.T) {
+	_, srv := newTestServer()
+
+	graphID := addGraph(t, srv, "g")
+
+	rec := do(t, srv, http.MethodPost, "/api/graphs", `{"name": "g"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /api/graphs duplicate status = %d, want %d",
+			rec.Code, http.StatusBadRequest)
+	}
+
+	rec = do(t, srv, http.MethodGet, "/api/graphs", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/graphs status = %d, want %d", rec.Code,
+			http.StatusOK)
+	}
+
+	var gs []entity.Graph
+	err := json.Unmarshal(rec.Body.Bytes(), &gs)
+	if err != nil {
+		t.Fatalf("GET /api/graphs body = %s: %v", rec.Body, err)
+	}
+	if len(gs) != 1 || gs[0].ID != graphID || !gs[0].Directed {
+		t.Errorf("GET /api/graphs got = %+v, want directed graph %d", gs,
+			graphID)
+	}
+
+	rec = do(t, srv, http.MethodDelete,
+		fmt.Sprintf("/api/graphs/%d", graphID), "")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE /api/graphs/%d status = %d, want %d", graphID,
+			rec.Code, http.StatusNoContent)
+	}
+
+	rec = do(t, srv, http.MethodPost,
+		fmt.Sprintf("/api/graphs/%d/clone", graphID), `{"name": "h"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /api/graphs/%d/clone status = %d, want %d",
+			graphID, rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestServer_postAPIVertexes_unknownGraph(t *testing.T) {
+	_, srv := newTestServer()
+
+	rec := do(t, srv, http.MethodPost, "/api/vertexes",
+		`{"graph_id": 100, "x": 1}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /api/vertexes status = %d, want %d", rec.Code,
+			http.StatusNotFound)
+	}
+}
+
+func TestServer_postAPIEdges(t *testing.T) {
+	s, srv := newTestServer()
+
+	graphID := addGraph(t, srv, "g")
+
+	rec := do(t, srv, http.MethodPost, "/api/vertexes",
+		fmt.Sprintf(`{"graph_id": %d}`, graphID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/vertexes status = %d, want %d", rec.Code,
+			http.StatusCreated)
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil || len(vs) != 1 {
+		t.Fatalf("Vertexes() got = %+v, %v, want 1 vertex", vs, err)
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/edges",
+		fmt.Sprintf(`{"graph_id": %d, "from": %d, "to": 100}`, graphID,
+			vs[0].ID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /api/edges unknown vertex status = %d, want %d",
+			rec.Code, http.StatusNotFound)
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/edges",
+		fmt.Sprintf(`{"graph_id": %d, "from": %d, "to": %d,
+			"directed": false}`, graphID, vs[0].ID, vs[0].ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/edges status = %d, want %d", rec.Code,
+			http.StatusCreated)
+	}
+
+	es, err := s.Edges(graphID)
+	if err != nil || len(es) != 1 || !es[0].Undirected {
+		t.Errorf("Edges() got = %+v, %v, want 1 undirected edge", es, err)
+	}
+}
+
+func TestServer_postAPIGraphBatch(t *testing.T) {
+	s, srv := newTestServer()
+
+	graphID := addGraph(t, srv, "g")
+	otherID := addGraph(t, srv, "h")
+
+	vertexID, err := s.AddVertex(entity.Vertex{GraphID: otherID})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+
+	path := fmt.Sprintf("/api/graphs/%d/batch", graphID)
+
+	// Edge to the vertex of other graph fails the whole batch.
+	rec := do(t, srv, http.MethodPost, path, fmt.Sprintf(`{"operations": [
+		{"type": "add-vertex", "vertex": {"id": -1}},
+		{"type": "add-edge", "edge": {"from": -1, "to": %d}}
+	]}`, vertexID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST %s status = %d, want %d", path, rec.Code,
+			http.StatusNotFound)
+	}
+
+	vs, err := s.Vertexes(graphID)
+	if err != nil || len(vs) != 0 {
+		t.Errorf("Vertexes() got = %+v, %v, want no vertexes", vs, err)
+	}
+
+	rec = do(t, srv, http.MethodPost, path, `{"operations": [
+		{"type": "add-vertex", "vertex": {"id": -1}},
+		{"type": "add-vertex", "vertex": {"id": -2}},
+		{"type": "add-edge", "edge": {"id": -1, "from": -1, "to": -2}}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST %s status = %d, want %d: %s", path, rec.Code,
+			http.StatusOK, rec.Body)
+	}
+
+	var res struct {
+		Vertexes map[int64]int64 `json:"vertexes"`
+		Edges    map[int64]int64 `json:"edges"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("POST %s body = %s: %v", path, rec.Body, err)
+	}
+
+	e, err := s.Edge(res.Edges[-1])
+	if err != nil {
+		t.Fatalf("Edge() error = %v", err)
+	}
+	if e.From != res.Vertexes[-1] || e.To != res.Vertexes[-2] {
+		t.Errorf("Edge() got = %+v, want edge from %d to %d", e,
+			res.Vertexes[-1], res.Vertexes[-2])
+	}
+}
+
+func TestServer_dumpRestore(t *testing.T) {
+	s, srv := newTestServer()
+
+	graphID := addGraph(t, srv, "g")
+
+	from, err := s.AddVertex(entity.Vertex{GraphID: graphID, X: 1})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+	to, err := s.AddVertex(entity.Vertex{GraphID: graphID, Y: 2})
+	if err != nil {
+		t.Fatalf("AddVertex() error = %v", err)
+	}
+	_, err = s.AddEdge(entity.Edge{GraphID: graphID, From: from, To: to,
+		Weight: 3})
+	if err != nil {
+		t.Fatalf("AddEdge() error = %v", err)
+	}
+
+	rec := do(t, srv, http.MethodGet, "/api/graphs/dump", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/graphs/dump status = %d, want %d", rec.Code,
+			http.StatusOK)
+	}
+	dump := rec.Body.String()
+
+	// Restoring into the same storage conflicts with existing graph.
+	rec = do(t, srv, http.MethodPost, "/api/graphs/restore", dump)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("POST /api/graphs/restore status = %d, want %d",
+			rec.Code, http.StatusConflict)
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/graphs/restore?name=h", dump)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/graphs/restore status = %d, want %d: %s",
+			rec.Code, http.StatusCreated, rec.Body)
+	}
+
+	var res struct {
+		Graphs []struct {
+			ID       int64  `json:"id"`
+			Name     string `json:"name"`
+			Vertexes int    `json:"vertexes"`
+			Edges    int    `json:"edges"`
+		} `json:"graphs"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("POST /api/graphs/restore body = %s: %v", rec.Body, err)
+	}
+	if len(res.Graphs) != 1 || res.Graphs[0].Name != "h" ||
+		res.Graphs[0].Vertexes != 2 || res.Graphs[0].Edges != 1 {
+		t.Fatalf("POST /api/graphs/restore got = %+v, want graph h "+
+			"with 2 vertexes and 1 edge", res)
+	}
+
+	es, err := s.Edges(res.Graphs[0].ID)
+	if err != nil || len(es) != 1 || es[0].Weight != 3 {
+		t.Errorf("Edges() got = %+v, %v, want 1 edge with weight 3", es,
+			err)
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// actorHeader is the request header with the name of the change author.
+const actorHeader = "X-Actor"
+
+// actorStorage returns storage which records the request actor as the
+// author of changes.
+func (s *Server) actorStorage(c echo.Context) Storage {
+	actor := c.Request().Header.Get(actorHeader)
+	if actor == "" {
+		return s.storage
+	}
+	return s.storage.WithActor(actor)
+}
+
+func (s *Server) getAPIGraphHistory(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	f := entity.ChangeFilter{Limit: defaultHistoryLimit}
+
+	switch f.Entity = c.QueryParam("entity"); f.Entity {
+	case "", entity.EntityGraph, entity.EntityVertex, entity.EntityEdge:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid entity")
+	}
+
+	if entityID := c.QueryParam("entity_id"); entityID != "" {
+		f.EntityID, err = strconv.ParseInt(entityID, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid entity_id")
+		}
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		f.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid since")
+		}
+	}
+
+	if until := c.QueryParam("until"); until != "" {
+		f.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid until")
+		}
+	}
+
+	if before := c.QueryParam("before"); before != "" {
+		f.BeforeID, err = strconv.ParseInt(before, 10, 64)
+		if err != nil || f.BeforeID <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid before")
+		}
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		f.Limit, err = strconv.Atoi(limit)
+		if err != nil || f.Limit <= 0 || f.Limit > maxHistoryLimit {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				"invalid limit")
+		}
+	}
+
+	cs, err := s.storage.Changes(graphID, f)
+	if err != nil {
+		return fmt.Errorf("get changes from storage: %w", err)
+	}
+
+	if cs == nil {
+		cs = []entity.Change{}
+	}
+
+	res := echo.Map{"changes": cs}
+
+	// next is the before value of the next page.
+	if len(cs) == f.Limit {
+		res["next"] = cs[len(cs)-1].ID
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
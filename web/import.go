@@ -0,0 +1,186 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/dimuls/graph/graphio"
+	"github.com/labstack/echo"
+)
+
+// maxImportSize is the maximum size of the imported file.
+const maxImportSize = 64 << 20
+
+// importGraph adds the graph with its vertexes and edges to the storage and
+// returns the new graph ID.
+func importGraph(s Storage, g graphio.Graph) (int64, error) {
+	graphID, err := s.AddGraph(g.Graph)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make(map[int64]int64, len(g.Vertexes))
+
+	for _, v := range g.Vertexes {
+		localID := v.ID
+		v.GraphID = graphID
+		v.ID, err = s.AddVertex(v)
+		if err != nil {
+			return 0, fmt.Errorf("add vertex: %w", err)
+		}
+		ids[localID] = v.ID
+	}
+
+	for _, e := range g.Edges {
+		e.GraphID = graphID
+		e.From, e.To = ids[e.From], ids[e.To]
+		_, err = s.AddEdge(e)
+		if err != nil {
+			return 0, fmt.Errorf("add edge: %w", err)
+		}
+	}
+
+	return graphID, nil
+}
+
+// readImportFile reads the imported file from the request body.
+func readImportFile(c echo.Context) (*bytes.Reader, error) {
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body,
+		maxImportSize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			"read request body: "+err.Error())
+	}
+
+	if len(data) > maxImportSize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("file is larger than %d bytes", maxImportSize))
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// readCSVImport reads the graph from vertices and edges files of the
+// multipart form or from the zip archive in the request body.
+func readCSVImport(c echo.Context) (graphio.Graph, error) {
+	req := c.Request()
+
+	if !strings.HasPrefix(req.Header.Get(echo.HeaderContentType),
+		echo.MIMEMultipartForm) {
+
+		r, err := readImportFile(c)
+		if err != nil {
+			return graphio.Graph{}, err
+		}
+		return graphio.ReadCSVZip(r, r.Size())
+	}
+
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxImportSize)
+
+	var files [2]io.ReadCloser
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+
+	for i, name := range []string{"vertices", "edges"} {
+		fh, err := c.FormFile(name)
+		if err != nil {
+			return graphio.Graph{}, echo.NewHTTPError(
+				http.StatusBadRequest, "read "+name+" file: "+err.Error())
+		}
+		files[i], err = fh.Open()
+		if err != nil {
+			return graphio.Graph{}, fmt.Errorf("open %s file: %w", name,
+				err)
+		}
+	}
+
+	return graphio.ReadCSV(files[0], files[1])
+}
+
+func (s *Server) postAPIGraphsImport(c echo.Context) error {
+	var (
+		g      graphio.Graph
+		report graphio.Report
+		err    error
+	)
+
+	switch c.QueryParam("format") {
+	case "graphml", "jgf", "gexf":
+		var r *bytes.Reader
+		r, err = readImportFile(c)
+		if err != nil {
+			return err
+		}
+		switch c.QueryParam("format") {
+		case "graphml":
+			g, report, err = graphio.ReadGraphML(r,
+				c.QueryParam("weight_key"))
+		case "jgf":
+			g, report, err = graphio.ReadJGF(r)
+		case "gexf":
+			g, report, err = graphio.ReadGEXF(r)
+		}
+	case "csv":
+		g, err = readCSVImport(c)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid format")
+	}
+	if err != nil {
+		if _, ok := err.(*echo.HTTPError); ok {
+			return err
+		}
+		var csvErr *graphio.CSVError
+		if errors.As(err, &csvErr) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error":  "read graph: " + err.Error(),
+				"errors": csvErr.Rows,
+			})
+		}
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"read graph: "+err.Error())
+	}
+
+	if name := c.QueryParam("name"); name != "" {
+		g.Graph.Name = name
+	}
+
+	if g.Graph.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"empty name")
+	}
+
+	var graphID int64
+
+	err = s.actorStorage(c).InTx(func(st Storage) (err error) {
+		graphID, err = importGraph(st, g)
+		return
+	})
+	if err != nil {
+		if err == entity.ErrDuplicatedGraphName {
+			return echo.NewHTTPError(http.StatusConflict, err)
+		}
+		return fmt.Errorf("import graph: %w", err)
+	}
+
+	if report.Skipped == nil {
+		report.Skipped = []graphio.Skipped{}
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"id":       graphID,
+		"vertexes": len(g.Vertexes),
+		"edges":    len(g.Edges),
+		"skipped":  report.Skipped,
+	})
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+)
+
+// Notification is the websocket message about graph changes sent to other
+// instances of the server.
+type Notification struct {
+	// Origin is the ID of the instance which made the changes. It already
+	// sent the message to its websocket listeners.
+	Origin  string `json:"origin"`
+	GraphID int64  `json:"graph_id"`
+
+	// Message is the websocket message. Empty message means that the fresh
+	// state of the graph should be sent to listeners instead.
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+// Notifier delivers notifications between instances of the server which
+// share the storage. If storage implements Notifier, websocket listeners
+// of every instance see changes made through any of them.
+type Notifier interface {
+	// Notify sends the notification to all instances. Storage given to f
+	// by InTx sends it when the transaction is committed and discards it
+	// otherwise, so the server notifies in the transaction of changes.
+	Notify(n Notification) error
+	// Listen calls f with notifications of all instances including own
+	// ones until stop is closed. Notification with zero GraphID means
+	// that notifications could be lost and all graphs should be sent
+	// again.
+	Listen(stop <-chan struct{}, f func(n Notification)) error
+}
+
+// newOrigin returns the random ID of the server instance.
+func newOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// graphRemovedMessage is the websocket message about the removed graph.
+var graphRemovedMessage = echo.Map{
+	"type": "graph-removed",
+}
+
+// publish encodes the websocket message about the changes made by st and
+// notifies other instances of the server about them in the same
+// transaction, so they get the notification if and only if the changes
+// are committed. The encoded message is sent to listeners of this instance
+// after the commit. Nil msg makes instances send the fresh state of the
+// graph.
+func (s *Server) publish(st Storage, graphID int64, msg echo.Map) ([]byte,
+	error) {
+
+	var data []byte
+
+	if msg != nil {
+		var err error
+		data, err = json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("encode websocket message: %w", err)
+		}
+	}
+
+	n, ok := st.(Notifier)
+	if !ok {
+		return data, nil
+	}
+
+	err := n.Notify(Notification{
+		Origin:  s.origin,
+		GraphID: graphID,
+		Message: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("notify other instances: %w", err)
+	}
+
+	return data, nil
+}
+
+// send sends the message to websocket listeners of the graph of this
+// instance. Listeners which fail to receive it are closed.
+func (s *Server) send(graphID int64, method string, data []byte) {
+	var failed []*websocket.Conn
+
+	s.graphListenersMx.RLock()
+	for ws := range s.graphListeners[graphID] {
+		err := websocket.Message.Send(ws, string(data))
+		if err != nil {
+			s.log.WithError(err).WithField("method", method).
+				Error("failed to send JSON to websocket")
+			failed = append(failed, ws)
+		}
+	}
+	s.graphListenersMx.RUnlock()
+
+	if len(failed) == 0 {
+		return
+	}
+
+	// Listener could be closed by the other sender meanwhile.
+	s.graphListenersMx.Lock()
+	for _, ws := range failed {
+		if closeWS, exists := s.graphListeners[graphID][ws]; exists {
+			close(closeWS)
+			delete(s.graphListeners[graphID], ws)
+		}
+	}
+	if len(s.graphListeners[graphID]) == 0 {
+		delete(s.graphListeners, graphID)
+	}
+	s.graphListenersMx.Unlock()
+}
+
+// closeListeners closes websocket listeners of the removed graph.
+func (s *Server) closeListeners(graphID int64) {
+	s.graphListenersMx.Lock()
+	for _, closeWS := range s.graphListeners[graphID] {
+		close(closeWS)
+	}
+	delete(s.graphListeners, graphID)
+	s.graphListenersMx.Unlock()
+}
+
+// removeGraph tells websocket listeners of this instance that the graph is
+// removed and closes them.
+func (s *Server) removeGraph(graphID int64, method string) {
+	data, _ := json.Marshal(graphRemovedMessage)
+	s.send(graphID, method, data)
+	s.closeListeners(graphID)
+}
+
+// listen delivers notifications of other instances to websocket listeners
+// of this one until the server is stopped.
+func (s *Server) listen() {
+	for {
+		err := s.notifier.Listen(s.stop, s.receive)
+		if err == nil {
+			return
+		}
+
+		s.log.WithError(err).Error("failed to listen notifications")
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(3 * time.Second):
+		}
+
+		// Notifications sent meanwhile are lost.
+		s.receive(Notification{})
+	}
+}
+
+// receive delivers the notification to websocket listeners of this
+// instance unless it is made by this instance.
+func (s *Server) receive(n Notification) {
+	const method = "receive"
+
+	if n.Origin == s.origin {
+		return
+	}
+
+	if n.GraphID == 0 {
+		s.graphListenersMx.RLock()
+		graphIDs := make([]int64, 0, len(s.graphListeners))
+		for graphID := range s.graphListeners {
+			graphIDs = append(graphIDs, graphID)
+		}
+		s.graphListenersMx.RUnlock()
+
+		for _, graphID := range graphIDs {
+			s.resendGraph(graphID)
+		}
+		return
+	}
+
+	if n.Message == nil {
+		s.resendGraph(n.GraphID)
+		return
+	}
+
+	var msg struct {
+		Type string `json:"type"`
+	}
+
+	err := json.Unmarshal(n.Message, &msg)
+	if err != nil {
+		s.log.WithError(err).WithField("method", method).
+			Error("failed to decode notification message")
+		return
+	}
+
+	if msg.Type == "graph-removed" {
+		s.removeGraph(n.GraphID, method)
+		return
+	}
+
+	s.send(n.GraphID, method, n.Message)
+}
+
+// resendGraph sends the fresh state of the graph to websocket listeners of
+// this instance.
+func (s *Server) resendGraph(graphID int64) {
+	const method = "resendGraph"
+
+	msg, err := s.graphMessage(graphID)
+	if err != nil {
+		if errors.Is(err, entity.ErrGraphNotFound) {
+			s.removeGraph(graphID, method)
+			return
+		}
+		s.log.WithError(err).WithField("method", method).
+			Error("failed to get graph")
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		s.log.WithError(err).WithField("method", method).
+			Error("failed to encode websocket message")
+		return
+	}
+
+	s.send(graphID, method, data)
+}
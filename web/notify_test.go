@@ -0,0 +1,108 @@
+package web_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dimuls/graph/memory"
+	"github.com/dimuls/graph/web"
+)
+
+// notifyingStorage records notifications sent in transaction if it is
+// committed like Postgres does. Notifications out of transaction fail, so
+// they are not sent after the commit.
+type notifyingStorage struct {
+	web.Storage
+	sent    *[]web.Notification
+	pending *[]web.Notification
+}
+
+func newNotifyingStorage() *notifyingStorage {
+	return &notifyingStorage{
+		Storage: memory.NewStorage(),
+		sent:    &[]web.Notification{},
+	}
+}
+
+func (s *notifyingStorage) Notify(n web.Notification) error {
+	if s.pending == nil {
+		return errors.New("notification out of transaction")
+	}
+	*s.pending = append(*s.pending, n)
+	return nil
+}
+
+func (s *notifyingStorage) Listen(stop <-chan struct{},
+	f func(n web.Notification)) error {
+
+	<-stop
+	return nil
+}
+
+func (s *notifyingStorage) InTx(f func(s web.Storage) error) error {
+	if s.pending != nil {
+		return f(s)
+	}
+
+	var pending []web.Notification
+
+	err := s.Storage.InTx(func(st web.Storage) error {
+		return f(&notifyingStorage{Storage: st, sent: s.sent,
+			pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+
+	*s.sent = append(*s.sent, pending...)
+
+	return nil
+}
+
+func (s *notifyingStorage) WithActor(actor string) web.Storage {
+	return &notifyingStorage{Storage: s.Storage.WithActor(actor),
+		sent: s.sent, pending: s.pending}
+}
+
+func TestServer_notify(t *testing.T) {
+	s := newNotifyingStorage()
+	srv := web.NewServer("", s)
+
+	graphID := addGraph(t, srv, "g")
+
+	rec := do(t, srv, http.MethodPost, "/api/vertexes",
+		`{"graph_id": 100}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST /api/vertexes status = %d, want %d", rec.Code,
+			http.StatusNotFound)
+	}
+	if len(*s.sent) != 0 {
+		t.Errorf("Notify() got = %+v after rollback, want none", *s.sent)
+	}
+
+	rec = do(t, srv, http.MethodPost, "/api/vertexes",
+		fmt.Sprintf(`{"graph_id": %d}`, graphID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/vertexes status = %d, want %d", rec.Code,
+			http.StatusCreated)
+	}
+
+	if len(*s.sent) != 1 {
+		t.Fatalf("Notify() got = %+v, want 1 notification", *s.sent)
+	}
+
+	n := (*s.sent)[0]
+
+	var msg struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(n.Message, &msg)
+	if err != nil || n.GraphID != graphID || n.Origin == "" ||
+		msg.Type != "new-vertex" {
+		t.Errorf("Notify() got = %+v, want new-vertex of graph %d", n,
+			graphID)
+	}
+}
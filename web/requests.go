@@ -0,0 +1,168 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+)
+
+// vertexRequest is a vertex sent by client. Nil fields are not set by the
+// client.
+type vertexRequest struct {
+	entity.Vertex
+	Label      *string            `json:"label"`
+	Attributes *entity.Attributes `json:"attributes"`
+}
+
+// vertex returns the requested vertex for adding.
+func (r vertexRequest) vertex() entity.Vertex {
+	v := r.Vertex
+	if r.Label != nil {
+		v.Label = *r.Label
+	}
+	if r.Attributes != nil {
+		v.Attributes = *r.Attributes
+	}
+	return v
+}
+
+// update returns the requested vertex for updating with fields not set by
+// the client taken from the stored vertex.
+func (r vertexRequest) update(s Storage) (entity.Vertex, error) {
+	old, err := s.Vertex(r.ID)
+	if err != nil {
+		return entity.Vertex{}, err
+	}
+
+	v := r.vertex()
+	v.GraphID = old.GraphID
+
+	if r.Label == nil {
+		v.Label = old.Label
+	}
+	if r.Attributes == nil {
+		v.Attributes = old.Attributes
+	}
+
+	return v, nil
+}
+
+// edgeRequest is an edge sent by client. Nil fields are not set by the
+// client.
+type edgeRequest struct {
+	entity.Edge
+	Directed   *bool              `json:"directed"`
+	Label      *string            `json:"label"`
+	Attributes *entity.Attributes `json:"attributes"`
+}
+
+// UnmarshalJSON decodes the edge and the fields set by client. It is
+// needed since entity.Edge decoder is promoted and skips other fields.
+func (r *edgeRequest) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &r.Edge)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Directed   *bool              `json:"directed"`
+		Label      *string            `json:"label"`
+		Attributes *entity.Attributes `json:"attributes"`
+	}
+
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return err
+	}
+
+	r.Directed = set.Directed
+	r.Label = set.Label
+	r.Attributes = set.Attributes
+
+	return nil
+}
+
+func (r edgeRequest) edge() entity.Edge {
+	e := r.Edge
+	if r.Directed != nil {
+		e.Undirected = !*r.Directed
+	}
+	if r.Label != nil {
+		e.Label = *r.Label
+	}
+	if r.Attributes != nil {
+		e.Attributes = *r.Attributes
+	}
+	return e
+}
+
+// add returns the requested edge for adding. Edge is directed as its
+// graph if the client has not set it.
+func (r edgeRequest) add(s Storage) (entity.Edge, error) {
+	e := r.edge()
+
+	if r.Directed == nil {
+		g, err := s.Graph(e.GraphID)
+		if err != nil {
+			return entity.Edge{}, err
+		}
+		e.Undirected = !g.Directed
+	}
+
+	return e, nil
+}
+
+// update returns the requested edge for updating with fields not set by
+// the client taken from the stored edge. Edge ends can not be changed.
+func (r edgeRequest) update(s Storage) (entity.Edge, error) {
+	old, err := s.Edge(r.ID)
+	if err != nil {
+		return entity.Edge{}, err
+	}
+
+	e := r.edge()
+	e.GraphID = old.GraphID
+	e.From = old.From
+	e.To = old.To
+
+	if r.Directed == nil {
+		e.Undirected = old.Undirected
+	}
+	if r.Label == nil {
+		e.Label = old.Label
+	}
+	if r.Attributes == nil {
+		e.Attributes = old.Attributes
+	}
+
+	return e, nil
+}
+
+// requestVersion returns the entity version known by the client. It is
+// taken from the request body if set there or from If-Match header.
+func requestVersion(c echo.Context, version int64) (int64, error) {
+	if version != 0 {
+		return version, nil
+	}
+
+	ifMatch := c.Request().Header.Get("If-Match")
+	if ifMatch == "" {
+		return 0, echo.NewHTTPError(http.StatusPreconditionRequired,
+			"version is required")
+	}
+
+	ifMatch = strings.TrimPrefix(ifMatch, "W/")
+	ifMatch = strings.Trim(ifMatch, `"`)
+
+	version, err := strconv.ParseInt(ifMatch, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest,
+			"invalid If-Match header")
+	}
+
+	return version, nil
+}
@@ -20,18 +20,56 @@ type Storage interface {
 	Graphs() ([]entity.Graph, error)
 	AddGraph(g entity.Graph) (int64, error)
 	RemoveGraph(graphID int64) error
+	// CloneGraph copies the graph with all its vertexes and edges into the
+	// new graph with the given name and returns its ID.
+	CloneGraph(graphID int64, name string) (int64, error)
 
 	Vertex(vertexID int64) (entity.Vertex, error)
 	Vertexes(graphID int64) ([]entity.Vertex, error)
 	AddVertex(v entity.Vertex) (int64, error)
-	SetVertex(v entity.Vertex) error
+	// SetVertex updates the vertex and returns its new version. The update
+	// fails with entity.ErrVersionConflict if non-zero v.Version differs
+	// from the stored one.
+	SetVertex(v entity.Vertex) (int64, error)
 	RemoveVertex(vertexID int64) error
 
 	Edge(edgeID int64) (entity.Edge, error)
 	Edges(graphID int64) ([]entity.Edge, error)
 	AddEdge(e entity.Edge) (int64, error)
-	SetEdge(e entity.Edge) error
+	// SetEdge updates the edge and returns its new version like SetVertex.
+	SetEdge(e entity.Edge) (int64, error)
 	RemoveEdge(edgeID int64) error
+
+	// InTx calls f with storage which applies all changes atomically: all
+	// of them if f returns nil and none otherwise.
+	InTx(f func(s Storage) error) error
+	// InReadTx calls f with storage which sees the consistent state of all
+	// data. f must not change data.
+	InReadTx(f func(s Storage) error) error
+
+	// Changes returns change log records of the graph selected by f,
+	// newest first. Every mutation is recorded in the same transaction.
+	Changes(graphID int64, f entity.ChangeFilter) ([]entity.Change, error)
+	// WithActor returns storage which records actor as the author of
+	// changes.
+	WithActor(actor string) Storage
+
+	// Undo reverts the last not undone transaction which changed vertexes
+	// or edges of the graph and returns the changes it made. Deleted
+	// vertexes and edges are restored with their original IDs.
+	Undo(graphID int64) ([]entity.Change, error)
+	// Redo applies again the last undone transaction like Undo. Undone
+	// transactions are discarded when the graph is changed otherwise.
+	Redo(graphID int64) ([]entity.Change, error)
+
+	Snapshots(graphID int64) ([]entity.Snapshot, error)
+	// AddSnapshot saves current vertexes and edges of the graph under the
+	// given name.
+	AddSnapshot(graphID int64, name string) (entity.Snapshot, error)
+	// RestoreSnapshot atomically replaces vertexes and edges of the graph
+	// with the saved ones keeping their IDs.
+	RestoreSnapshot(graphID int64, snapshotID int64) error
+	RemoveSnapshot(graphID int64, snapshotID int64) error
 }
 
 type Server struct {
@@ -44,20 +82,35 @@ type Server struct {
 
 	graphListeners   map[int64]map[*websocket.Conn]chan struct{}
 	graphListenersMx sync.RWMutex
+
+	// notifier is set if storage is shared by several instances of the
+	// server, origin is the ID of this instance in notifications.
+	notifier Notifier
+	origin   string
 }
 
 func NewServer(bindAddr string, s Storage) *Server {
-	return &Server{
+	notifier, _ := s.(Notifier)
+
+	srv := &Server{
 		bindAddr:       bindAddr,
 		storage:        s,
 		log:            logrus.WithField("subsystem", "web_server"),
 		graphListeners: map[int64]map[*websocket.Conn]chan struct{}{},
+		notifier:       notifier,
+		origin:         newOrigin(),
 	}
+	srv.echo = srv.newEcho()
+
+	return srv
 }
 
-func (s *Server) Start() {
-	s.log.WithField("bind_addr", s.bindAddr).Info("starting")
+// ServeHTTP handles the request without starting the server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.echo.ServeHTTP(w, r)
+}
 
+func (s *Server) newEcho() *echo.Echo {
 	e := echo.New()
 
 	e.HideBanner = true
@@ -104,9 +157,30 @@ func (s *Server) Start() {
 
 	api.GET("/graphs", s.getAPIGraphs)
 	api.POST("/graphs", s.postAPIGraphs)
+	api.POST("/graphs/import", s.postAPIGraphsImport)
+	api.GET("/graphs/dump", s.getAPIGraphsDump)
+	api.POST("/graphs/restore", s.postAPIGraphsRestore)
 	api.GET("/graphs/:graph_id", s.getAPIGraph)
 	api.DELETE("/graphs/:graph_id", s.deleteAPIGraph)
+	api.POST("/graphs/:graph_id/clone", s.postAPIGraphClone)
+	api.GET("/graphs/:graph_id/export", s.getAPIGraphExport)
+	api.GET("/graphs/:graph_id/dump", s.getAPIGraphDump)
 	api.GET("/graphs/:graph_id/shortest-path", s.getAPIGraphShortestPath)
+	api.GET("/graphs/:graph_id/k-shortest-paths",
+		s.getAPIGraphKShortestPaths)
+	api.GET("/graphs/:graph_id/distances", s.getAPIGraphDistances)
+	api.GET("/graphs/:graph_id/distance-matrix",
+		s.getAPIGraphDistanceMatrix)
+	api.POST("/graphs/:graph_id/batch", s.postAPIGraphBatch)
+	api.GET("/graphs/:graph_id/history", s.getAPIGraphHistory)
+	api.POST("/graphs/:graph_id/undo", s.postAPIGraphUndo)
+	api.POST("/graphs/:graph_id/redo", s.postAPIGraphRedo)
+	api.GET("/graphs/:graph_id/snapshots", s.getAPIGraphSnapshots)
+	api.POST("/graphs/:graph_id/snapshots", s.postAPIGraphSnapshots)
+	api.POST("/graphs/:graph_id/snapshots/:snapshot_id/restore",
+		s.postAPIGraphSnapshotRestore)
+	api.DELETE("/graphs/:graph_id/snapshots/:snapshot_id",
+		s.deleteAPIGraphSnapshot)
 
 	api.POST("/vertexes", s.postAPIVertexes)
 	api.PUT("/vertexes", s.putAPIVertexes)
@@ -116,7 +190,12 @@ func (s *Server) Start() {
 	api.PUT("/edges", s.putAPIEdges)
 	api.DELETE("/edges/:edge_id", s.deleteAPIEdge)
 
-	s.echo = e
+	return e
+}
+
+func (s *Server) Start() {
+	s.log.WithField("bind_addr", s.bindAddr).Info("starting")
+
 	s.stop = make(chan struct{})
 
 	s.wg.Add(1)
@@ -134,6 +213,14 @@ func (s *Server) Start() {
 			}
 		}
 	}()
+
+	if s.notifier != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.listen()
+		}()
+	}
 }
 
 func (s *Server) Stop() {
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+)
+
+func (s *Server) getAPIGraphSnapshots(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	ss, err := s.storage.Snapshots(graphID)
+	if err != nil {
+		return fmt.Errorf("get snapshots from storage: %w", err)
+	}
+
+	if ss == nil {
+		ss = []entity.Snapshot{}
+	}
+
+	return c.JSON(http.StatusOK, ss)
+}
+
+func (s *Server) postAPIGraphSnapshots(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	err = c.Bind(&req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"bind snapshot: "+err.Error())
+	}
+
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"empty name")
+	}
+
+	ss, err := s.storage.AddSnapshot(graphID, req.Name)
+	if err != nil {
+		switch err {
+		case entity.ErrGraphNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		case entity.ErrDuplicatedSnapshotName:
+			return echo.NewHTTPError(http.StatusConflict, err)
+		}
+		return fmt.Errorf("add snapshot to storage: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, ss)
+}
+
+func (s *Server) postAPIGraphSnapshotRestore(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	snapshotID, err := strconv.ParseInt(c.Param("snapshot_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid snapshot_id")
+	}
+
+	// Other instances load the graph themselves since it could be too
+	// large for the notification.
+	err = s.actorStorage(c).InTx(func(st Storage) error {
+		err := st.RestoreSnapshot(graphID, snapshotID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.publish(st, graphID, nil)
+		return err
+	})
+	if err != nil {
+		if err == entity.ErrGraphNotFound ||
+			err == entity.ErrSnapshotNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return fmt.Errorf("restore snapshot: %w", err)
+	}
+
+	err = s.sendGraph(graphID)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) deleteAPIGraphSnapshot(c echo.Context) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	snapshotID, err := strconv.ParseInt(c.Param("snapshot_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid snapshot_id")
+	}
+
+	err = s.storage.RemoveSnapshot(graphID, snapshotID)
+	if err != nil {
+		if err == entity.ErrSnapshotNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return fmt.Errorf("remove snapshot from storage: %w", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// sendGraph sends the fresh state of the graph to websocket listeners of
+// this instance.
+func (s *Server) sendGraph(graphID int64) error {
+	msg, err := s.graphMessage(graphID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode websocket message: %w", err)
+	}
+
+	s.send(graphID, "sendGraph", data)
+
+	return nil
+}
+
+// graphMessage returns the websocket message with the fresh state of the
+// graph.
+func (s *Server) graphMessage(graphID int64) (echo.Map, error) {
+	g, err := s.storage.Graph(graphID)
+	if err != nil {
+		return nil, fmt.Errorf("get graph from storage: %w", err)
+	}
+
+	vs, err := s.storage.Vertexes(graphID)
+	if err != nil {
+		return nil, fmt.Errorf("get vertexes from storage: %w", err)
+	}
+
+	if vs == nil {
+		vs = []entity.Vertex{}
+	}
+
+	es, err := s.storage.Edges(graphID)
+	if err != nil {
+		return nil, fmt.Errorf("get edges from storage: %w", err)
+	}
+
+	if es == nil {
+		es = []entity.Edge{}
+	}
+
+	return echo.Map{
+		"type": "set-graph",
+		"data": echo.Map{
+			"graph":    g,
+			"vertexes": vs,
+			"edges":    es,
+		},
+	}, nil
+}
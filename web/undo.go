@@ -0,0 +1,127 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dimuls/graph/entity"
+	"github.com/labstack/echo"
+)
+
+// changeMessages converts vertex and edge changes to websocket messages.
+func changeMessages(cs []entity.Change) ([]echo.Map, error) {
+	var msgs []echo.Map
+
+	for _, c := range cs {
+		var (
+			typ  string
+			data json.RawMessage
+		)
+
+		switch c.Entity {
+		case entity.EntityVertex:
+			switch c.Operation {
+			case entity.OperationInsert:
+				typ, data = "new-vertex", c.After
+			case entity.OperationUpdate:
+				typ, data = "vertex-update", c.After
+			case entity.OperationDelete:
+				typ, data = "vertex-removed", c.Before
+			}
+		case entity.EntityEdge:
+			switch c.Operation {
+			case entity.OperationInsert:
+				typ, data = "new-edge", c.After
+			case entity.OperationUpdate:
+				typ, data = "edge-update", c.After
+			case entity.OperationDelete:
+				typ, data = "edge-removed", c.Before
+			}
+		}
+
+		if typ == "" {
+			continue
+		}
+
+		var v interface{}
+		if c.Entity == entity.EntityVertex {
+			v = &entity.Vertex{}
+		} else {
+			v = &entity.Edge{}
+		}
+
+		err := json.Unmarshal(data, v)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal change %d: %w", c.ID, err)
+		}
+
+		msgs = append(msgs, echo.Map{"type": typ, "data": v})
+	}
+
+	return msgs, nil
+}
+
+func (s *Server) postAPIGraphUndo(c echo.Context) error {
+	return s.applyHistoryStep(c, true)
+}
+
+func (s *Server) postAPIGraphRedo(c echo.Context) error {
+	return s.applyHistoryStep(c, false)
+}
+
+func (s *Server) applyHistoryStep(c echo.Context, undo bool) error {
+	graphID, err := strconv.ParseInt(c.Param("graph_id"),
+		10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"invalid graph_id")
+	}
+
+	var (
+		cs   []entity.Change
+		data []byte
+	)
+
+	err = s.actorStorage(c).InTx(func(st Storage) (err error) {
+		if undo {
+			cs, err = st.Undo(graphID)
+		} else {
+			cs, err = st.Redo(graphID)
+		}
+		if err != nil {
+			return err
+		}
+
+		msgs, err := changeMessages(cs)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		data, err = s.publish(st, graphID, echo.Map{
+			"type": "batch",
+			"data": msgs,
+		})
+		return err
+	})
+	if err != nil {
+		switch err {
+		case entity.ErrGraphNotFound:
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		case entity.ErrNothingToUndo, entity.ErrNothingToRedo:
+			return echo.NewHTTPError(http.StatusConflict, err)
+		}
+		return fmt.Errorf("apply history step: %w", err)
+	}
+
+	if data != nil {
+		s.send(graphID, "applyHistoryStep", data)
+	}
+
+	if cs == nil {
+		cs = []entity.Change{}
+	}
+
+	return c.JSON(http.StatusOK, cs)
+}